@@ -43,6 +43,10 @@ var (
 	ConvertInsecureRepo          bool
 	ConvertDeploymentConfig      bool
 	ConvertReplicas              int
+	ConvertNetworkPolicies       bool
+	ConvertWaitForDependencies   bool
+	ConvertExternalLinksDomain   string
+	ConvertBindMounts            string
 	ConvertOpt                   kobject.ConvertOptions
 )
 
@@ -84,6 +88,10 @@ var convertCmd = &cobra.Command{
 			IsReplicationControllerFlag: cmd.Flags().Lookup("replication-controller").Changed,
 			IsReplicaSetFlag:            cmd.Flags().Lookup("replicas").Changed,
 			IsDeploymentConfigFlag:      cmd.Flags().Lookup("deployment-config").Changed,
+			NetworkPolicies:             ConvertNetworkPolicies,
+			WaitForDependencies:         ConvertWaitForDependencies,
+			ExternalLinksDomain:         ConvertExternalLinksDomain,
+			BindMounts:                  ConvertBindMounts,
 		}
 
 		// Validate before doing anything else. Use "bundle" if passed in.
@@ -106,10 +114,12 @@ func init() {
 	convertCmd.Flags().BoolVar(&ConvertDaemonSet, "daemon-set", false, "Generate a Kubernetes daemonset object")
 	convertCmd.Flags().BoolVarP(&ConvertDeployment, "deployment", "d", false, "Generate a Kubernetes deployment object")
 	convertCmd.Flags().BoolVar(&ConvertReplicationController, "replication-controller", false, "Generate a Kubernetes replication controller object")
+	convertCmd.Flags().BoolVar(&ConvertNetworkPolicies, "network-policies", false, "Generate a Kubernetes network policy for each network")
 	convertCmd.Flags().MarkHidden("chart")
 	convertCmd.Flags().MarkHidden("daemon-set")
 	convertCmd.Flags().MarkHidden("replication-controller")
 	convertCmd.Flags().MarkHidden("deployment")
+	convertCmd.Flags().MarkHidden("network-policies")
 
 	// OpenShift only
 	convertCmd.Flags().BoolVar(&ConvertDeploymentConfig, "deployment-config", true, "Generate an OpenShift deploymentconfig object")
@@ -131,6 +141,9 @@ func init() {
 	convertCmd.Flags().BoolVar(&ConvertEmptyVols, "emptyvols", false, "Use Empty Volumes. Do not generate PVCs")
 	convertCmd.Flags().StringVarP(&ConvertOut, "out", "o", "", "Specify a file name to save objects to")
 	convertCmd.Flags().IntVar(&ConvertReplicas, "replicas", 1, "Specify the number of repliaces in the generate resource spec")
+	convertCmd.Flags().BoolVar(&ConvertWaitForDependencies, "wait-for-dependencies", false, "Generate init containers waiting for the services in depends_on")
+	convertCmd.Flags().StringVar(&ConvertBindMounts, "bind-mounts", kobject.BindMountsPVC, `Set how the volumes mounting a path on the host are converted ("pvc"|"hostpath"|"emptydir")`)
+	convertCmd.Flags().StringVar(&ConvertExternalLinksDomain, "external-links-domain", "", "Specify the domain of the containers in external_links")
 
 	// In order to 'separate' both OpenShift and Kubernetes only flags. A custom help page is created
 	customHelp := `Usage:{{if .Runnable}}
@@ -150,6 +163,7 @@ Kubernetes Flags:
       --daemon-set               Generate a Kubernetes daemonset object
   -d, --deployment               Generate a Kubernetes deployment object
   -c, --chart                    Create a Helm chart for converted objects
+      --network-policies         Generate a Kubernetes network policy for each network
       --replication-controller   Generate a Kubernetes replication controller object
 
 OpenShift Flags:
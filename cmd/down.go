@@ -17,6 +17,7 @@ limitations under the License.
 package cmd
 
 import (
+	log "github.com/Sirupsen/logrus"
 	"strings"
 
 	"github.com/kubernetes/kompose/pkg/app"
@@ -26,8 +27,9 @@ import (
 
 // TODO: comment
 var (
-	DownNamespace string
-	DownOpt       kobject.ConvertOptions
+	DownNamespace       string
+	DownOpt             kobject.ConvertOptions
+	DownNetworkPolicies bool
 )
 
 var downCmd = &cobra.Command{
@@ -36,12 +38,17 @@ var downCmd = &cobra.Command{
 	Long:  `Delete instantiated services/deployments from kubernetes. (default "kubernetes")`,
 	PreRun: func(cmd *cobra.Command, args []string) {
 
+		if strings.ToLower(GlobalProvider) == "openshift" && DownNetworkPolicies {
+			log.Fatalf("--network-policies is a Kubernetes only flag")
+		}
+
 		// Create the Convert options.
 		DownOpt = kobject.ConvertOptions{
 			InputFiles:      GlobalFiles,
 			Provider:        strings.ToLower(GlobalProvider),
 			Namespace:       DownNamespace,
 			IsNamespaceFlag: cmd.Flags().Lookup("namespace").Changed,
+			NetworkPolicies: DownNetworkPolicies,
 		}
 
 		// Validate before doing anything else.
@@ -54,5 +61,6 @@ var downCmd = &cobra.Command{
 
 func init() {
 	downCmd.Flags().StringVar(&DownNamespace, "namespace", "default", " Specify Namespace to deploy your application")
+	downCmd.Flags().BoolVar(&DownNetworkPolicies, "network-policies", false, "Delete the Kubernetes network policies of the networks too")
 	RootCmd.AddCommand(downCmd)
 }
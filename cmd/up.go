@@ -27,12 +27,13 @@ import (
 
 // TODO: comment
 var (
-	UpReplicas     int
-	UpEmptyVols    bool
-	UpInsecureRepo bool
-	UpNamespace    string
-	UpOpt          kobject.ConvertOptions
-	UpBuild        string
+	UpReplicas        int
+	UpEmptyVols       bool
+	UpInsecureRepo    bool
+	UpNamespace       string
+	UpOpt             kobject.ConvertOptions
+	UpBuild           string
+	UpNetworkPolicies bool
 )
 
 var upCmd = &cobra.Command{
@@ -46,6 +47,9 @@ var upCmd = &cobra.Command{
 		if provider == "kubernetes" && UpBuild == "build-config" {
 			log.Fatalf("build-config is not a valid --build parameter with provider Kubernetes")
 		}
+		if provider == "openshift" && UpNetworkPolicies {
+			log.Fatalf("--network-policies is a Kubernetes only flag")
+		}
 
 		// Create the Convert options.
 		UpOpt = kobject.ConvertOptions{
@@ -57,6 +61,7 @@ var upCmd = &cobra.Command{
 			Namespace:          UpNamespace,
 			InsecureRepository: UpInsecureRepo,
 			IsNamespaceFlag:    cmd.Flags().Lookup("namespace").Changed,
+			NetworkPolicies:    UpNetworkPolicies,
 		}
 
 		// Validate before doing anything else.
@@ -73,5 +78,6 @@ func init() {
 	upCmd.Flags().BoolVar(&UpInsecureRepo, "insecure-repository", false, "Use an insecure Docker repository for OpenShift ImageStream")
 	upCmd.Flags().StringVar(&UpNamespace, "namespace", "default", "Specify Namespace to deploy your application")
 	upCmd.Flags().StringVar(&UpBuild, "build", "local", `Set the type of build ("local"|"build-config" (OpenShift only)|"none")`)
+	upCmd.Flags().BoolVar(&UpNetworkPolicies, "network-policies", false, "Create a Kubernetes network policy for each network")
 	RootCmd.AddCommand(upCmd)
 }
@@ -75,6 +75,7 @@ func ValidateFlags(bundle string, args []string, cmd *cobra.Command, opt *kobjec
 	daemonSet := cmd.Flags().Lookup("daemon-set").Changed
 	replicationController := cmd.Flags().Lookup("replication-controller").Changed
 	deployment := cmd.Flags().Lookup("deployment").Changed
+	networkPolicies := cmd.Flags().Lookup("network-policies").Changed
 
 	// Check validations against provider flags
 	switch {
@@ -91,6 +92,9 @@ func ValidateFlags(bundle string, args []string, cmd *cobra.Command, opt *kobjec
 		if deployment {
 			log.Fatalf("--deployment, -d is a Kubernetes only flag")
 		}
+		if networkPolicies {
+			log.Fatalf("--network-policies is a Kubernetes only flag")
+		}
 	case provider == "kubernetes":
 		if deploymentConfig {
 			log.Fatalf("--deployment-config is an OpenShift only flag")
@@ -116,6 +120,12 @@ func ValidateFlags(bundle string, args []string, cmd *cobra.Command, opt *kobjec
 		log.Fatalf("Error: --replicas cannot be negative")
 	}
 
+	switch opt.BindMounts {
+	case kobject.BindMountsPVC, kobject.BindMountsHostPath, kobject.BindMountsEmptyDir:
+	default:
+		log.Fatalf("Error: --bind-mounts must be %s, %s or %s", kobject.BindMountsPVC, kobject.BindMountsHostPath, kobject.BindMountsEmptyDir)
+	}
+
 	if len(bundle) > 0 {
 		inputFormat = "bundle"
 		log.Fatalf("DAB / bundle (--bundle | -b) is no longer supported. See issue: https://github.com/kubernetes/kompose/issues/390")
@@ -24,6 +24,11 @@ import (
 // KomposeObject holds the generic struct of Kompose transformation
 type KomposeObject struct {
 	ServiceConfigs map[string]ServiceConfig
+	// NetworkConfigs holds the networks by name, including the implicit default network
+	NetworkConfigs map[string]NetworkConfig
+	// Secrets and Configs hold the top level secrets and configs by name
+	Secrets map[string]FileObject
+	Configs map[string]FileObject
 	// LoadedFrom is name of the loader that created KomposeObject
 	// Transformer need to know origin format in order to tell user what tag is not supported in origin format
 	// as they can have different names. For example environment variables  are called environment in compose but Env in bundle.
@@ -56,8 +61,21 @@ type ConvertOptions struct {
 	IsReplicaSetFlag            bool
 	IsDeploymentConfigFlag      bool
 	IsNamespaceFlag             bool
+	NetworkPolicies             bool
+	WaitForDependencies         bool
+	ExternalLinksDomain         string
+	// BindMounts is how the volumes mounting a path on the host are converted, BindMountsPVC by default
+	BindMounts string
 }
 
+// Strategies converting the bind mounts, to PersistentVolumeClaims ignoring the path on the host,
+// to hostPath volumes, or to emptyDir volumes
+const (
+	BindMountsPVC      = "pvc"
+	BindMountsHostPath = "hostpath"
+	BindMountsEmptyDir = "emptydir"
+)
+
 // ServiceConfig holds the basic struct of a container
 type ServiceConfig struct {
 	// use tags to mark from what element this value comes
@@ -80,6 +98,9 @@ type ServiceConfig struct {
 	CPUReservation  int64               `compose:"" bundle:""`
 	CapAdd          []string            `compose:"cap_add" bundle:""`
 	CapDrop         []string            `compose:"cap_drop" bundle:""`
+	SecurityOpt     []string            `compose:"security_opt" bundle:""`
+	ReadOnly        bool                `compose:"read_only" bundle:""`
+	GroupAdd        []string            `compose:"group_add" bundle:""`
 	Expose          []string            `compose:"expose" bundle:""`
 	Pid             string              `compose:"pid" bundle:""`
 	Privileged      bool                `compose:"privileged" bundle:""`
@@ -88,34 +109,171 @@ type ServiceConfig struct {
 	VolumesFrom     []string            `compose:"volumes_from" bundle:""`
 	ServiceType     string              `compose:"kompose.service.type" bundle:""`
 	StopGracePeriod string              `compose:"stop_grace_period" bundle:""`
+	StopSignal      string              `compose:"stop_signal" bundle:""`
 	Build           string              `compose:"build" bundle:""`
 	BuildArgs       map[string]*string  `compose:"build-args" bundle:""`
+	BuildTarget     string              `compose:"build-target" bundle:""`
+	BuildLabels     map[string]string   `compose:"build-labels" bundle:""`
+	BuildCacheFrom  []string            `compose:"build-cache-from" bundle:""`
 	ExposeService   string              `compose:"kompose.service.expose" bundle:""`
 	Stdin           bool                `compose:"stdin_open" bundle:""`
 	Tty             bool                `compose:"tty" bundle:""`
 	MemLimit        yaml.MemStringorInt `compose:"mem_limit" bundle:""`
 	MemReservation  yaml.MemStringorInt `compose:"" bundle:""`
 	TmpFs           []string            `compose:"tmpfs" bundle:""`
+	ShmSize         yaml.MemStringorInt `compose:"shm_size" bundle:""`
 	Dockerfile      string              `compose:"dockerfile" bundle:""`
 	Replicas        int                 `compose:"replicas" bundle:""`
+	HealthChecks    HealthCheck         `compose:"healthcheck" bundle:""`
+	EnvFile         []EnvFile           `compose:"env_file" bundle:""`
+	Secrets         []FileReference     `compose:"secrets" bundle:""`
+	Configs         []FileReference     `compose:"configs" bundle:""`
+	DependsOn       []ServiceDependency `compose:"depends_on" bundle:""`
+	Links           []Link              `compose:"links" bundle:""`
+	ExternalLinks   []Link              `compose:"external_links" bundle:""`
+	Hostname        string              `compose:"hostname" bundle:""`
+	DomainName      string              `compose:"domainname" bundle:""`
+	Sysctls         map[string]string   `compose:"sysctls" bundle:""`
+	Ulimits         []Ulimit            `compose:"ulimits" bundle:""`
+	// WrapUlimitNofile wraps the command of the container in a shell setting the nofile ulimit
+	WrapUlimitNofile bool      `compose:"kompose.service.wrap_ulimit_nofile" bundle:""`
+	Placement        Placement `compose:"placement" bundle:""`
+	// DeployMode is "global" for a service running on every node, and "replicated" otherwise
+	DeployMode     string        `compose:"deploy.mode" bundle:""`
+	UpdateConfig   *UpdateConfig `compose:"deploy.update_config" bundle:""`
+	RollbackConfig *UpdateConfig `compose:"deploy.rollback_config" bundle:""`
+	// WaitForDependencies adds init containers waiting for DependsOn, even without --wait-for-dependencies
+	WaitForDependencies bool `compose:"kompose.service.wait_for_dependencies" bundle:""`
+	// PodGroup is the service whose pod the container of this service joins,
+	// from network_mode: "service:<name>" or the kompose.pod.group label
+	PodGroup string `compose:"kompose.pod.group" bundle:""`
+	// Containers are the services joining the pod of this service, each one is a container of the pod
+	Containers []ServiceConfig `compose:"" bundle:""`
 	// Volumes is a struct which contains all information about each volume
 	Volumes []Volumes `compose:"" bundle:""`
 }
 
+// Conditions of a ServiceDependency
+const (
+	ServiceStarted = "service_started"
+	ServiceHealthy = "service_healthy"
+)
+
+// Ulimit holds the soft and hard limits of a ulimit
+type Ulimit struct {
+	Name string
+	Soft int64
+	Hard int64
+}
+
+// UpdateConfig holds the deploy.update_config or the deploy.rollback_config of a service
+type UpdateConfig struct {
+	// Parallelism is the number of containers updated at once, all of them when it is 0
+	Parallelism uint64
+	// Delay and Monitor are in seconds
+	Delay           int32
+	Monitor         int32
+	FailureAction   string
+	MaxFailureRatio float64
+	// Order is either "stop-first" or "start-first"
+	Order string
+}
+
+// Placement holds the deploy.placement constraints and preferences of a service
+type Placement struct {
+	Constraints []PlacementConstraint
+	// Preferences are the node attributes the replicas are spread over
+	Preferences []string
+}
+
+// PlacementConstraint holds a constraint on a node attribute, like "node.labels.zone == east"
+type PlacementConstraint struct {
+	Key string
+	// Operator is either "==" or "!="
+	Operator string
+	Value    string
+}
+
+// ServiceDependency holds a service that a service depends on, and the condition the service waits for
+type ServiceDependency struct {
+	Service   string
+	Condition string
+}
+
+// Link is a link to a service, or to an external container, reachable at Alias
+type Link struct {
+	Service string
+	Alias   string
+	// ExternalName is the DNS name of an external container, from the kompose.service.external_link.<alias> label
+	ExternalName string
+}
+
+// NetworkConfig holds the configuration of a network
+type NetworkConfig struct {
+	Internal bool // the network isn't reachable from outside
+}
+
+// HealthCheck the healthcheck configuration for a service
+// Durations are stored in seconds
+type HealthCheck struct {
+	Test        []string
+	Timeout     int32
+	Interval    int32
+	Retries     int32
+	StartPeriod int32
+	Disable     bool
+	// HTTPGetPath and HTTPGetPort turn the probe into an HTTP GET request
+	// TCPPort turns the probe into a TCP socket check
+	// these are set by kompose labels, as compose has no equivalent
+	HTTPGetPath string
+	HTTPGetPort int32
+	TCPPort     int32
+}
+
 // EnvVar holds the environment variable struct of a container
 type EnvVar struct {
 	Name  string
 	Value string
 }
 
+// EnvFile holds the variables read from an env_file, which are converted to a ConfigMap
+type EnvFile struct {
+	Name        string // name of the ConfigMap
+	Path        string // path of the file as given in docker-compose file
+	Environment []EnvVar
+}
+
+// FileObject holds a secret or a config whose content is read from a file, or which already exists.
+// The content is stored under a key named after the Kubernetes object.
+type FileObject struct {
+	Name     string // name of the Kubernetes object
+	Data     []byte // content of the file
+	External bool   // the object already exists and is not created
+}
+
+// FileReference holds a reference of a service to a secret or a config, mounted as a file in the container
+type FileReference struct {
+	Source string // name of the secret or config in docker-compose file
+	Name   string // name of the Kubernetes object
+	Target string // absolute path of the file in the container
+	UID    string
+	GID    string
+	Mode   *int32
+}
+
 // Ports holds the ports struct of a container
 type Ports struct {
 	HostPort      int32
 	ContainerPort int32
 	HostIP        string
 	Protocol      api.Protocol
+	// Mode is PortModeHost for a port published on the node only, which is a host port instead of a Service port
+	Mode string
 }
 
+// PortModeHost is the mode of the ports published on the node running the container, set with the long syntax
+const PortModeHost = "host"
+
 // Volumes holds the volume struct of container
 type Volumes struct {
 	SvcName    string // Service name to which volume is linked
@@ -126,4 +284,17 @@ type Volumes struct {
 	Container  string // Mountpath
 	Mode       string // access mode for volume
 	PVCName    string // name of PVC
+	// These are set from the top level volumes key for named volumes
+	PVCSize      string // requested storage, defaults to PVCRequestSize
+	StorageClass string // storage class of the PVC
+	AccessMode   string // access mode of the PVC, overrides Mode
+	External     bool   // the PVC already exists and is not created
+	Type         string // VolumeTypeBind for a path on the host, else VolumeTypeVolume
+	SubPath      string // path in the volume to mount instead of its root, from the volume.subpath long syntax key
 }
+
+// Types of the volumes of a service, the tmpfs volumes are in TmpFs
+const (
+	VolumeTypeBind   = "bind"
+	VolumeTypeVolume = "volume"
+)
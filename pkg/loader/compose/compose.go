@@ -17,7 +17,6 @@ limitations under the License.
 package compose
 
 import (
-	"fmt"
 	"io/ioutil"
 	"reflect"
 	"strings"
@@ -46,52 +45,25 @@ func checkUnsupportedKey(composeProject *project.Project) []string {
 	// to make sure that unsupported key is not going to be reported twice
 	// by keeping record if already saw this key in another service
 	var unsupportedKey = map[string]bool{
-		"CgroupParent":  false,
-		"CPUSet":        false,
-		"CPUShares":     false,
-		"Devices":       false,
-		"DependsOn":     false,
-		"DNS":           false,
-		"DNSSearch":     false,
-		"DomainName":    false,
-		"EnvFile":       false,
-		"ExternalLinks": false,
-		"ExtraHosts":    false,
-		"Hostname":      false,
-		"Ipc":           false,
-		"Logging":       false,
-		"MacAddress":    false,
-		"MemSwapLimit":  false,
-		"NetworkMode":   false,
-		"SecurityOpt":   false,
-		"ShmSize":       false,
-		"StopSignal":    false,
-		"VolumeDriver":  false,
-		"Uts":           false,
-		"ReadOnly":      false,
-		"Ulimits":       false,
-		"Net":           false,
-		"Sysctls":       false,
-		"Networks":      false, // there are special checks for Network in checkUnsupportedKey function
+		"CgroupParent": false,
+		"CPUSet":       false,
+		"CPUShares":    false,
+		"Devices":      false,
+		"DNS":          false,
+		"DNSSearch":    false,
+		"ExtraHosts":   false,
+		"Ipc":          false,
+		"Logging":      false,
+		"MacAddress":   false,
+		"MemSwapLimit": false,
+		"VolumeDriver": false,
+		"Uts":          false,
+		"Net":          false,
 	}
 
 	// collect all keys found in project
 	var keysFound []string
 
-	// Root level keys are not yet supported
-	// Check to see if the default network is available and length is only equal to one.
-	// Else, warn the user that root level networks are not supported (yet)
-	if _, ok := composeProject.NetworkConfigs["default"]; ok && len(composeProject.NetworkConfigs) == 1 {
-		log.Debug("Default network found")
-	} else if len(composeProject.NetworkConfigs) > 0 {
-		keysFound = append(keysFound, "root level networks")
-	}
-
-	// Root level volumes are not yet supported
-	if len(composeProject.VolumeConfigs) > 0 {
-		keysFound = append(keysFound, "root level volumes")
-	}
-
 	for _, serviceConfig := range composeProject.ServiceConfigs.All() {
 		// this reflection is used in check for empty arrays
 		val := reflect.ValueOf(serviceConfig).Elem()
@@ -111,15 +83,6 @@ func checkUnsupportedKey(composeProject *project.Project) []string {
 					}
 					//get yaml tag name instad of variable name
 					yamlTagName := strings.Split(f.Tag("yaml"), ",")[0]
-					if f.Name() == "Networks" {
-						// networks always contains one default element, even it isn't declared in compose v2.
-						if len(serviceConfig.Networks.Networks) == 1 && serviceConfig.Networks.Networks[0].Name == "default" {
-							// this is empty Network definition, skip it
-							continue
-						} else {
-							yamlTagName = "networks"
-						}
-					}
 					keysFound = append(keysFound, yamlTagName)
 					unsupportedKey[f.Name()] = true
 				}
@@ -150,27 +113,31 @@ func (c *Compose) LoadFile(files []string) (kobject.KomposeObject, error) {
 
 	log.Debugf("Docker Compose version: %s", version)
 
+	parsedVersion, err := parseComposeVersion(version)
+	if err != nil {
+		return kobject.KomposeObject{}, err
+	}
+
 	// Convert based on version
-	switch version {
+	var komposeObject kobject.KomposeObject
+	switch parsedVersion.major {
 	// Use libcompose for 1 or 2
 	// If blank, it's assumed it's 1 or 2
-	case "", "1", "1.0", "2", "2.0":
-		komposeObject, err := parseV1V2(files)
-		if err != nil {
-			return kobject.KomposeObject{}, err
-		}
-		return komposeObject, nil
+	case 1, 2:
+		komposeObject, err = parseV1V2(files, parsedVersion)
 	// Use docker/cli for 3
-	case "3", "3.0":
-		komposeObject, err := parseV3(files)
-		if err != nil {
-			return kobject.KomposeObject{}, err
-		}
-		return komposeObject, nil
 	default:
-		return kobject.KomposeObject{}, fmt.Errorf("Version %s of Docker Compose is not supported. Please use version 1, 2 or 3", version)
+		komposeObject, err = parseV3(files, parsedVersion)
+	}
+	if err != nil {
+		return kobject.KomposeObject{}, err
 	}
 
+	// The services sharing the network of another service are containers of its pod
+	if err := groupPodServices(&komposeObject); err != nil {
+		return kobject.KomposeObject{}, err
+	}
+	return komposeObject, nil
 }
 
 func getVersionFromFile(file string) (string, error) {
@@ -17,7 +17,9 @@ limitations under the License.
 package compose
 
 import (
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -68,6 +70,367 @@ func TestLoadV3Ports(t *testing.T) {
 	}
 }
 
+// Test if healthcheck is parsed properly, durations are converted to seconds
+// and CMD / CMD-SHELL tests are converted to a command
+func TestParseHealthCheck(t *testing.T) {
+	retries := uint64(2)
+	testCases := map[string]struct {
+		input  types.HealthCheckConfig
+		output kobject.HealthCheck
+	}{
+		"CMD": {
+			types.HealthCheckConfig{
+				Test:        types.HealthCheckTest{"CMD", "echo", "foobar"},
+				Timeout:     "1s",
+				Interval:    "2s",
+				Retries:     &retries,
+				StartPeriod: "3s",
+			},
+			kobject.HealthCheck{
+				Test:        []string{"echo", "foobar"},
+				Timeout:     1,
+				Interval:    2,
+				Retries:     2,
+				StartPeriod: 3,
+			},
+		},
+		"CMD-SHELL": {
+			types.HealthCheckConfig{
+				Test:     types.HealthCheckTest{"CMD-SHELL", "curl -f http://localhost || exit 1"},
+				Interval: "1m30s",
+			},
+			kobject.HealthCheck{
+				Test:     []string{"/bin/sh", "-c", "curl -f http://localhost || exit 1"},
+				Interval: 90,
+			},
+		},
+		"NONE": {
+			types.HealthCheckConfig{
+				Test: types.HealthCheckTest{"NONE"},
+			},
+			kobject.HealthCheck{
+				Disable: true,
+			},
+		},
+	}
+
+	for name, test := range testCases {
+		output, err := parseHealthCheck(test.input)
+		if err != nil {
+			t.Errorf("Test case %q: unexpected error %v", name, err)
+		}
+		if !reflect.DeepEqual(output, test.output) {
+			t.Errorf("Test case %q: expected %#v, got %#v", name, test.output, output)
+		}
+	}
+
+	_, err := parseHealthCheck(types.HealthCheckConfig{Timeout: "abc"})
+	if err == nil {
+		t.Errorf("Expected an error for invalid timeout")
+	}
+}
+
+// Test loading of the raw healthcheck key of v2.1 files
+func TestLoadHealthCheck(t *testing.T) {
+	rawHealthCheck := map[interface{}]interface{}{
+		"test":     "curl -f http://localhost",
+		"interval": "10s",
+		"retries":  3,
+		"disable":  false,
+	}
+	retries := uint64(3)
+	expected := types.HealthCheckConfig{
+		Test:     types.HealthCheckTest{"CMD-SHELL", "curl -f http://localhost"},
+		Interval: "10s",
+		Retries:  &retries,
+	}
+
+	output, err := loadHealthCheck(rawHealthCheck)
+	if err != nil {
+		t.Errorf("Unexpected error with loading healthcheck %v", err)
+	}
+	if !reflect.DeepEqual(output, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, output)
+	}
+
+	_, err = loadHealthCheck(map[interface{}]interface{}{"foo": "bar"})
+	if err == nil {
+		t.Errorf("Expected an error for unknown healthcheck key")
+	}
+}
+
+// Test if the short and long syntax of the v3 build key are loaded, relative to the compose file directory
+func TestLoadV3Build(t *testing.T) {
+	os.Setenv("osfoo", "osbar")
+
+	testCases := map[string]struct {
+		rawBuild  interface{}
+		expected  kobject.ServiceConfig
+		expectErr bool
+	}{
+		"Context only": {
+			"./build",
+			kobject.ServiceConfig{Build: "/project/build"},
+			false,
+		},
+		"Remote context": {
+			"git://github.com/foo/bar.git",
+			kobject.ServiceConfig{Build: "git://github.com/foo/bar.git"},
+			false,
+		},
+		"All keys": {
+			map[string]interface{}{
+				"context":    ".",
+				"dockerfile": "Dockerfile-alt",
+				"target":     "prod",
+				"args":       []interface{}{"foo=bar", "osfoo"},
+				"labels":     map[string]interface{}{"com.example": "web"},
+				"cache_from": []interface{}{"foo/bar:latest"},
+			},
+			kobject.ServiceConfig{
+				Build:          "/project",
+				Dockerfile:     "Dockerfile-alt",
+				BuildTarget:    "prod",
+				BuildArgs:      map[string]*string{"foo": &[]string{"bar"}[0], "osfoo": &[]string{"osbar"}[0]},
+				BuildLabels:    map[string]string{"com.example": "web"},
+				BuildCacheFrom: []string{"foo/bar:latest"},
+			},
+			false,
+		},
+		"Missing context": {map[string]interface{}{"dockerfile": "Dockerfile"}, kobject.ServiceConfig{}, true},
+		"Unknown key":     {map[string]interface{}{"context": ".", "foo": "bar"}, kobject.ServiceConfig{}, true},
+		"Invalid type":    {1, kobject.ServiceConfig{}, true},
+	}
+
+	for testName, test := range testCases {
+		t.Log("Test case:", testName)
+		serviceConfig := kobject.ServiceConfig{}
+		err := loadV3Build(test.rawBuild, "/project", &serviceConfig)
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("Expected an error, got %#v", serviceConfig)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(serviceConfig, test.expected) {
+			t.Errorf("Expected %#v, got %#v", test.expected, serviceConfig)
+		}
+	}
+}
+
+func TestMergeV3ComposeFiles(t *testing.T) {
+	base := map[string]interface{}{
+		"version": "3",
+		"services": map[string]interface{}{
+			"foo": map[string]interface{}{
+				"image":       "foo:1",
+				"environment": []interface{}{"FOO=1", "BAR=1"},
+				"ports":       []interface{}{"80", "443"},
+				"volumes":     []interface{}{"/data", "./config:/etc/foo:ro"},
+				"build":       "./foo",
+				"deploy": map[string]interface{}{
+					"replicas":  1,
+					"labels":    map[string]interface{}{"a": "1"},
+					"placement": map[string]interface{}{"constraints": []interface{}{"node.role == manager"}},
+				},
+			},
+			"bar": map[string]interface{}{
+				"image": "bar",
+			},
+		},
+		"volumes": map[string]interface{}{
+			"data": map[string]interface{}{},
+		},
+		"x-version": 1,
+		"x-tags":    []interface{}{"a"},
+	}
+	override := map[string]interface{}{
+		"version":   "3",
+		"x-version": 2,
+		"services": map[string]interface{}{
+			"foo": map[string]interface{}{
+				"image":       "foo:2",
+				"environment": map[string]interface{}{"BAR": "2", "BAZ": nil},
+				"ports":       []interface{}{"443", "8080"},
+				"volumes":     []interface{}{"data:/data"},
+				"build":       map[string]interface{}{"dockerfile": "Dockerfile-prod"},
+				"deploy": map[string]interface{}{
+					"labels":    []interface{}{"b=2"},
+					"placement": map[string]interface{}{"constraints": []interface{}{"node.labels.zone == a"}},
+				},
+			},
+			"baz": map[string]interface{}{
+				"image": "baz",
+			},
+		},
+	}
+	expected := map[string]interface{}{
+		"version": "3",
+		"services": map[string]interface{}{
+			"foo": map[string]interface{}{
+				"image":       "foo:2",
+				"environment": map[string]interface{}{"FOO": "1", "BAR": "2", "BAZ": nil},
+				"ports":       []interface{}{"80", "443", "8080"},
+				"volumes":     []interface{}{"data:/data", "./config:/etc/foo:ro"},
+				"build":       map[string]interface{}{"context": "./foo", "dockerfile": "Dockerfile-prod"},
+				// the nested lists are replaced
+				"deploy": map[string]interface{}{
+					"replicas":  1,
+					"labels":    map[string]interface{}{"a": "1", "b": "2"},
+					"placement": map[string]interface{}{"constraints": []interface{}{"node.labels.zone == a"}},
+				},
+			},
+			"bar": map[string]interface{}{
+				"image": "bar",
+			},
+			"baz": map[string]interface{}{
+				"image": "baz",
+			},
+		},
+		"volumes": map[string]interface{}{
+			"data": map[string]interface{}{},
+		},
+		"x-version": 2,
+		"x-tags":    []interface{}{"a"},
+	}
+
+	merged, err := mergeV3ComposeFiles([]map[string]interface{}{base, override})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(merged, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, merged)
+	}
+}
+
+func TestParseComposeVersion(t *testing.T) {
+	testCases := map[string]struct {
+		version   string
+		expected  composeVersion
+		expectErr bool
+	}{
+		"No version":          {"", composeVersion{major: 1}, false},
+		"Version 2":           {"2", composeVersion{major: 2}, false},
+		"Version 2.4":         {"2.4", composeVersion{major: 2, minor: 4}, false},
+		"Version 3.9":         {"3.9", composeVersion{major: 3, minor: 9}, false},
+		"Unsupported minor":   {"2.5", composeVersion{}, true},
+		"Unsupported major":   {"4", composeVersion{}, true},
+		"Version 1.1":         {"1.1", composeVersion{}, true},
+		"Invalid version":     {"3.x", composeVersion{}, true},
+		"Too many components": {"3.1.1", composeVersion{}, true},
+	}
+
+	for name, test := range testCases {
+		t.Log("Test case:", name)
+		version, err := parseComposeVersion(test.version)
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("Expected an error, got %v", version)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if version != test.expected {
+			t.Errorf("Expected %v, got %v", test.expected, version)
+		}
+	}
+}
+
+func TestServiceKeyVersions(t *testing.T) {
+	service := map[string]interface{}{
+		"image": "foo",
+		"init":  true,
+		"deploy": map[string]interface{}{
+			"replicas":      2,
+			"update_config": map[string]interface{}{"order": "start-first"},
+		},
+	}
+
+	if err := checkServiceKeyVersions("foo", service, composeVersion{major: 3, minor: 4}); err == nil {
+		t.Errorf("Expected an error for init in version 3.4")
+	}
+	if err := checkServiceKeyVersions("foo", service, composeVersion{major: 3, minor: 7}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	removeUnparsableServiceKeys(service, composeVersion{major: 3, minor: 7}, map[string]bool{})
+	expected := map[string]interface{}{
+		"image": "foo",
+		"deploy": map[string]interface{}{
+			"replicas":      2,
+			"update_config": map[string]interface{}{},
+		},
+	}
+	if !reflect.DeepEqual(service, expected) {
+		t.Errorf("Expected %v, got %v", expected, service)
+	}
+}
+
+func TestParseV3TopLevelNames(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kompose-top-level-names")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	composeFile := `version: "3.7"
+services:
+  web:
+    image: nginx
+    networks:
+      - front
+    volumes:
+      - data:/data
+    secrets:
+      - token
+volumes:
+  data:
+    name: my_data
+networks:
+  front:
+    name: public
+secrets:
+  token:
+    name: app-token
+    file: ./token
+`
+	file := filepath.Join(dir, "docker-compose.yml")
+	if err := ioutil.WriteFile(file, []byte(composeFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "token"), []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	komposeObject, err := parseV3([]string{file}, composeVersion{major: 3, minor: 7})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	web := komposeObject.ServiceConfigs["web"]
+	if len(web.Volumes) != 1 || web.Volumes[0].PVCName != "my-data" {
+		t.Errorf("Expected the claim my-data, got %#v", web.Volumes)
+	}
+	if !reflect.DeepEqual(web.Network, []string{"public"}) {
+		t.Errorf("Expected the network public, got %v", web.Network)
+	}
+	if _, ok := komposeObject.NetworkConfigs["public"]; !ok {
+		t.Errorf("Expected the network public, got %v", komposeObject.NetworkConfigs)
+	}
+	if len(web.Secrets) != 1 || web.Secrets[0].Name != "app-token" {
+		t.Errorf("Expected the secret app-token, got %#v", web.Secrets)
+	}
+
+	// the name of volumes is from 3.4, and the name of secrets from 3.5
+	if _, err := parseV3([]string{file}, composeVersion{major: 3, minor: 4}); err == nil {
+		t.Errorf("Expected an error for the name of a secret in version 3.4")
+	}
+}
+
 // Test if service types are parsed properly on user input
 // give a service type and expect correct input
 func TestHandleServiceType(t *testing.T) {
@@ -144,6 +507,77 @@ func TestLoadPorts(t *testing.T) {
 	}
 }
 
+func TestLoadPortSpec(t *testing.T) {
+	testCases := map[string]struct {
+		port     string
+		expected []kobject.Ports
+		fail     bool
+	}{
+		"ranges": {"8000-8001:80-81", []kobject.Ports{
+			{HostPort: 8000, ContainerPort: 80, Protocol: api.ProtocolTCP},
+			{HostPort: 8001, ContainerPort: 81, Protocol: api.ProtocolTCP},
+		}, false},
+		"container range": {"9090-9091/udp", []kobject.Ports{
+			{ContainerPort: 9090, Protocol: api.ProtocolUDP},
+			{ContainerPort: 9091, Protocol: api.ProtocolUDP},
+		}, false},
+		"ranges with IP": {"127.0.0.1:9000-9001:9000-9001", []kobject.Ports{
+			{HostIP: "127.0.0.1", HostPort: 9000, ContainerPort: 9000, Protocol: api.ProtocolTCP},
+			{HostIP: "127.0.0.1", HostPort: 9001, ContainerPort: 9001, Protocol: api.ProtocolTCP},
+		}, false},
+		"IP without host port": {"127.0.0.1::80", []kobject.Ports{
+			{HostIP: "127.0.0.1", ContainerPort: 80, Protocol: api.ProtocolTCP},
+		}, false},
+		"host range for a single port": {"8000-8010:80", []kobject.Ports{
+			{HostPort: 8000, ContainerPort: 80, Protocol: api.ProtocolTCP},
+		}, false},
+		"ranges of different sizes": {"8000-8010:80-81", nil, true},
+		"reversed range":            {"81-80", nil, true},
+	}
+	for name, test := range testCases {
+		t.Log("Test case:", name)
+		ports, err := loadPortSpec(test.port)
+		if test.fail {
+			if err == nil {
+				t.Errorf("Expected an error, got %#v", ports)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(ports, test.expected) {
+			t.Errorf("Expected %#v, got %#v", test.expected, ports)
+		}
+	}
+}
+
+func TestLoadV3RawPorts(t *testing.T) {
+	rawPorts := []interface{}{
+		3000,
+		"127.0.0.1:8001:8001",
+		map[string]interface{}{"target": 443, "published": "8443"},
+		map[string]interface{}{"target": 9100, "published": 9100, "protocol": "udp", "mode": "host"},
+	}
+	expected := []kobject.Ports{
+		{ContainerPort: 3000, Protocol: api.ProtocolTCP},
+		{HostIP: "127.0.0.1", HostPort: 8001, ContainerPort: 8001, Protocol: api.ProtocolTCP},
+		{HostPort: 8443, ContainerPort: 443, Protocol: api.ProtocolTCP},
+		{HostPort: 9100, ContainerPort: 9100, Protocol: api.ProtocolUDP, Mode: kobject.PortModeHost},
+	}
+	ports, err := loadV3RawPorts(rawPorts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(ports, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, ports)
+	}
+
+	if _, err := loadV3RawPorts([]interface{}{map[string]interface{}{"target": 80, "mode": "swarm"}}); err == nil {
+		t.Errorf("Expected an error for an invalid mode")
+	}
+}
+
 func TestLoadEnvVar(t *testing.T) {
 	ev1 := []string{"foo=bar"}
 	rs1 := kobject.EnvVar{
@@ -210,6 +644,510 @@ func TestLoadEnvVar(t *testing.T) {
 	}
 }
 
+func TestLoadEnvFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kompose-env-file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "common.env"), []byte("# comment\nfoo=bar\n\nosfoo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("osfoo", "osbar")
+
+	envFiles, err := loadEnvFiles("web", []string{"./common.env"}, dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []kobject.EnvFile{
+		{
+			Name: "web-common-env",
+			Path: "./common.env",
+			Environment: []kobject.EnvVar{
+				{Name: "foo", Value: "bar"},
+				{Name: "osfoo", Value: "osbar"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(envFiles, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, envFiles)
+	}
+
+	if _, err := loadEnvFiles("web", []string{"missing.env"}, dir); err == nil {
+		t.Errorf("Expected an error for a missing env_file")
+	}
+
+	// paths sanitized to the same name get distinct ConfigMaps
+	if err := ioutil.WriteFile(filepath.Join(dir, "common_env"), []byte("foo=baz\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	envFiles, err = loadEnvFiles("web", []string{"common.env", "./common_env", "common.env"}, dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var names []string
+	for _, envFile := range envFiles {
+		names = append(names, envFile.Name)
+	}
+	expectedNames := []string{"web-common-env", "web-common-env-2", "web-common-env-3"}
+	if !reflect.DeepEqual(names, expectedNames) {
+		t.Errorf("Expected ConfigMap names %v, got %v", expectedNames, names)
+	}
+
+	longName := strings.Repeat("a", 250) + ".env"
+	if err := ioutil.WriteFile(filepath.Join(dir, longName), []byte("foo=bar\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadEnvFiles("web", []string{longName}, dir); err == nil {
+		t.Errorf("Expected an error for a ConfigMap name longer than 253 characters")
+	}
+}
+
+func TestLoadEnvFilePaths(t *testing.T) {
+	testCases := map[string]struct {
+		rawEnvFile interface{}
+		expected   []string
+		expectErr  bool
+	}{
+		"String":       {"a.env", []string{"a.env"}, false},
+		"List":         {[]interface{}{"a.env", "b.env"}, []string{"a.env", "b.env"}, false},
+		"Invalid type": {1, nil, true},
+		"Invalid item": {[]interface{}{1}, nil, true},
+	}
+
+	for name, test := range testCases {
+		t.Log("Test case:", name)
+		paths, err := loadEnvFilePaths(test.rawEnvFile)
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("Expected an error, got %v", paths)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(paths, test.expected) {
+			t.Errorf("Expected %v, got %v", test.expected, paths)
+		}
+	}
+}
+
+func TestLoadNamedVolume(t *testing.T) {
+	testCases := map[string]struct {
+		driverOpts   map[string]string
+		labels       map[string]string
+		external     bool
+		externalName string
+		expected     namedVolume
+		expectErr    bool
+	}{
+		"Default": {nil, nil, false, "", namedVolume{claimName: "db-data"}, false},
+		"Driver options": {
+			map[string]string{"size": "1Gi", "storage-class": "fast", "access-mode": "ReadWriteMany"}, nil, false, "",
+			namedVolume{claimName: "db-data", size: "1Gi", storageClass: "fast", accessMode: "ReadWriteMany"}, false,
+		},
+		"Labels override driver options": {
+			map[string]string{"size": "1Gi"}, map[string]string{"kompose.volume.size": "2Gi"}, false, "",
+			namedVolume{claimName: "db-data", size: "2Gi"}, false,
+		},
+		"External":             {nil, nil, true, "", namedVolume{claimName: "db-data", external: true}, false},
+		"External with name":   {nil, nil, true, "existing", namedVolume{claimName: "existing", external: true}, false},
+		"External underscores": {nil, nil, true, "existing_data", namedVolume{claimName: "existing-data", external: true}, false},
+		"Invalid size":         {map[string]string{"size": "big"}, nil, false, "", namedVolume{}, true},
+		"Invalid access mode":  {nil, map[string]string{"kompose.volume.access-mode": "rw"}, false, "", namedVolume{}, true},
+	}
+
+	for name, test := range testCases {
+		t.Log("Test case:", name)
+		volume, err := loadNamedVolume("db_data", test.driverOpts, test.labels, test.external, test.externalName)
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("Expected an error, got %v", volume)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if volume != test.expected {
+			t.Errorf("Expected %#v, got %#v", test.expected, volume)
+		}
+	}
+}
+
+func TestShareNamedVolumes(t *testing.T) {
+	komposeObject := kobject.KomposeObject{
+		ServiceConfigs: map[string]kobject.ServiceConfig{
+			"web": {VolList: []string{"data:/data:ro", "/tmp"}},
+			"db":  {VolList: []string{"data:/var/lib/data", "backups:/backups"}},
+		},
+	}
+	namedVolumes := map[string]namedVolume{
+		"data":    {claimName: "data", size: "1Gi"},
+		"backups": {claimName: "existing-backups", external: true},
+	}
+	handleVolume(&komposeObject, namedVolumes)
+
+	db := komposeObject.ServiceConfigs["db"].Volumes
+	web := komposeObject.ServiceConfigs["web"].Volumes
+	// db is the first service mounting data, so it creates the claim
+	if db[0].VFrom != "" || db[0].PVCName != "data" || db[0].PVCSize != "1Gi" || db[0].AccessMode != "ReadWriteOnce" {
+		t.Errorf("Unexpected volume %#v", db[0])
+	}
+	if web[0].VFrom != "db" || web[0].PVCName != "data" || web[0].PVCSize != "1Gi" {
+		t.Errorf("Unexpected volume %#v", web[0])
+	}
+	if !db[1].External || db[1].PVCName != "existing-backups" {
+		t.Errorf("Unexpected volume %#v", db[1])
+	}
+	if web[1].PVCName != "web-claim1" || web[1].AccessMode != "" {
+		t.Errorf("Unexpected volume %#v", web[1])
+	}
+}
+
+func TestLoadFileReference(t *testing.T) {
+	mode := uint32(0400)
+	mode32 := int32(0400)
+	secrets := map[string]kobject.FileObject{
+		"db_password": {Name: "db-password"},
+		"api_key":     {Name: "api-key", External: true},
+	}
+	testCases := map[string]struct {
+		source    string
+		target    string
+		mode      *uint32
+		expected  kobject.FileReference
+		expectErr bool
+	}{
+		"Default target":  {"db_password", "", nil, kobject.FileReference{Source: "db_password", Name: "db-password", Target: "/run/secrets/db_password"}, false},
+		"Relative target": {"db_password", "password", &mode, kobject.FileReference{Source: "db_password", Name: "db-password", Target: "/run/secrets/password", Mode: &mode32}, false},
+		"Absolute target": {"api_key", "/etc/api/key", nil, kobject.FileReference{Source: "api_key", Name: "api-key", Target: "/etc/api/key"}, false},
+		"Undefined":       {"tls_cert", "", nil, kobject.FileReference{}, true},
+	}
+
+	configs := map[string]kobject.FileObject{"nginx_conf": {Name: "nginx-conf"}}
+	reference, err := loadFileReference("config", "nginx_conf", "", "", "", nil, configs)
+	if err != nil || reference.Target != "/nginx_conf" {
+		t.Errorf("Expected the config to be mounted at /nginx_conf, got %#v (%v)", reference, err)
+	}
+
+	for name, test := range testCases {
+		t.Log("Test case:", name)
+		reference, err := loadFileReference("secret", test.source, test.target, "", "", test.mode, secrets)
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("Expected an error, got %v", reference)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(reference, test.expected) {
+			t.Errorf("Expected %#v, got %#v", test.expected, reference)
+		}
+	}
+}
+
+func TestLoadUpdateConfig(t *testing.T) {
+	testCases := map[string]struct {
+		rawUpdateConfig interface{}
+		expected        *kobject.UpdateConfig
+		expectErr       bool
+	}{
+		"Defaults": {
+			map[string]interface{}{},
+			&kobject.UpdateConfig{Parallelism: 1, Order: "stop-first"},
+			false,
+		},
+		"All keys": {
+			map[string]interface{}{"parallelism": 2, "delay": "1m30s", "monitor": "10s", "failure_action": "rollback", "max_failure_ratio": 0.5, "order": "start-first"},
+			&kobject.UpdateConfig{Parallelism: 2, Delay: 90, Monitor: 10, FailureAction: "rollback", MaxFailureRatio: 0.5, Order: "start-first"},
+			false,
+		},
+		"Invalid order":   {map[string]interface{}{"order": "random"}, nil, true},
+		"Invalid delay":   {map[string]interface{}{"delay": "soon"}, nil, true},
+		"Unsupported key": {map[string]interface{}{"batch": 2}, nil, true},
+		"Invalid type":    {"parallelism", nil, true},
+	}
+
+	for name, test := range testCases {
+		t.Log("Test case:", name)
+		updateConfig, err := loadUpdateConfig("deploy.update_config", test.rawUpdateConfig)
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("Expected an error, got %#v", updateConfig)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(updateConfig, test.expected) {
+			t.Errorf("Expected %#v, got %#v", test.expected, updateConfig)
+		}
+	}
+}
+
+func TestGroupPodServices(t *testing.T) {
+	komposeObject := kobject.KomposeObject{ServiceConfigs: map[string]kobject.ServiceConfig{
+		"web":    {Image: "nginx"},
+		"app":    {Image: "app", PodGroup: "web", DependsOn: []kobject.ServiceDependency{{Service: "web", Condition: kobject.ServiceStarted}}},
+		"logger": {Image: "fluentd", ContainerName: "log", PodGroup: "app", DependsOn: []kobject.ServiceDependency{{Service: "db", Condition: kobject.ServiceStarted}}, WaitForDependencies: true},
+		"db":     {Image: "redis", DependsOn: []kobject.ServiceDependency{{Service: "app", Condition: kobject.ServiceStarted}}},
+	}}
+	if err := groupPodServices(&komposeObject); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(komposeObject.ServiceConfigs) != 2 {
+		t.Fatalf("Expected the services web and db, got %v", komposeObject.ServiceConfigs)
+	}
+	web := komposeObject.ServiceConfigs["web"]
+	if len(web.Containers) != 2 || web.Containers[0].ContainerName != "app" || web.Containers[1].ContainerName != "log" {
+		t.Errorf("Expected the containers app and log in the pod of web, got %#v", web.Containers)
+	}
+	expectedDependsOn := []kobject.ServiceDependency{{Service: "db", Condition: kobject.ServiceStarted}}
+	if !reflect.DeepEqual(web.DependsOn, expectedDependsOn) {
+		t.Errorf("Expected web to depend on %v, got %v", expectedDependsOn, web.DependsOn)
+	}
+	if !web.WaitForDependencies {
+		t.Errorf("Expected web to wait for its dependencies, as its container log does")
+	}
+	if len(web.Containers) == 2 && len(web.Containers[0].DependsOn) != 0 {
+		t.Errorf("Expected the dependency of app on its own pod to be removed, got %v", web.Containers[0].DependsOn)
+	}
+	expectedDependsOn = []kobject.ServiceDependency{{Service: "web", Condition: kobject.ServiceStarted}}
+	if !reflect.DeepEqual(komposeObject.ServiceConfigs["db"].DependsOn, expectedDependsOn) {
+		t.Errorf("Expected db to depend on %v, got %v", expectedDependsOn, komposeObject.ServiceConfigs["db"].DependsOn)
+	}
+
+	testCases := map[string]map[string]kobject.ServiceConfig{
+		"Unknown service": {"app": {PodGroup: "web"}},
+		"Cycle":           {"app": {PodGroup: "web"}, "web": {PodGroup: "app"}},
+	}
+	for name, services := range testCases {
+		t.Log("Test case:", name)
+		if err := groupPodServices(&kobject.KomposeObject{ServiceConfigs: services}); err == nil {
+			t.Errorf("Expected an error")
+		}
+	}
+}
+
+func TestLoadPlacement(t *testing.T) {
+	placement := loadPlacement("web", []string{"node.labels.zone==east", "node.role != manager", "node=foo"}, []string{"node.labels.zone"})
+	expected := kobject.Placement{
+		Constraints: []kobject.PlacementConstraint{
+			{Key: "node.labels.zone", Operator: "==", Value: "east"},
+			{Key: "node.role", Operator: "!=", Value: "manager"},
+		},
+		Preferences: []string{"node.labels.zone"},
+	}
+	if !reflect.DeepEqual(placement, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, placement)
+	}
+}
+
+func TestExtractV3TmpfsVolumes(t *testing.T) {
+	service := map[string]interface{}{
+		"volumes": []interface{}{
+			"data:/data",
+			map[string]interface{}{"type": "tmpfs", "target": "/run"},
+			map[string]interface{}{"type": "tmpfs", "target": "/cache", "tmpfs": map[string]interface{}{"size": 1000}},
+		},
+	}
+	tmpfsVolumes := extractV3TmpfsVolumes(service)
+	expected := []interface{}{"/run", "/cache:size=1000"}
+	if !reflect.DeepEqual(tmpfsVolumes, expected) {
+		t.Errorf("Expected tmpfs %v, got %v", expected, tmpfsVolumes)
+	}
+	if !reflect.DeepEqual(service["volumes"], []interface{}{"data:/data"}) {
+		t.Errorf("Expected the other volumes to be kept, got %v", service["volumes"])
+	}
+
+	service = map[string]interface{}{"volumes": []interface{}{map[string]interface{}{"type": "tmpfs", "target": "/run"}}}
+	extractV3TmpfsVolumes(service)
+	if _, ok := service["volumes"]; ok {
+		t.Errorf("Expected the volumes key to be removed, got %v", service["volumes"])
+	}
+}
+
+func TestExtractV3VolumeSubPaths(t *testing.T) {
+	service := map[string]interface{}{
+		"volumes": []interface{}{
+			"data:/data",
+			map[string]interface{}{"type": "volume", "source": "data", "target": "/html", "volume": map[string]interface{}{"nocopy": true, "subpath": "html"}},
+		},
+	}
+	subPaths := extractV3VolumeSubPaths(service)
+	if !reflect.DeepEqual(subPaths, map[string]interface{}{"/html": "html"}) {
+		t.Errorf("Expected the subpath of /html, got %v", subPaths)
+	}
+	volume := service["volumes"].([]interface{})[1].(map[string]interface{})
+	if !reflect.DeepEqual(volume["volume"], map[string]interface{}{"nocopy": true}) {
+		t.Errorf("Expected the subpath to be removed, got %v", volume["volume"])
+	}
+}
+
+func TestParseVolsType(t *testing.T) {
+	volumes, err := ParseVols([]string{"data:/data", "/srv/conf:/etc/conf:ro", "/cache"}, "web")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []string{kobject.VolumeTypeVolume, kobject.VolumeTypeBind, kobject.VolumeTypeVolume}
+	for i, volume := range volumes {
+		if volume.Type != expected[i] {
+			t.Errorf("Expected type %s for %s, got %s", expected[i], volume.MountPath, volume.Type)
+		}
+	}
+}
+
+func TestLoadSysctls(t *testing.T) {
+	testCases := map[string]struct {
+		rawSysctls interface{}
+		expected   map[string]string
+		expectErr  bool
+	}{
+		"List":           {[]interface{}{"net.core.somaxconn=1024"}, map[string]string{"net.core.somaxconn": "1024"}, false},
+		"Map":            {map[string]interface{}{"net.core.somaxconn": 1024}, map[string]string{"net.core.somaxconn": "1024"}, false},
+		"libcompose map": {map[interface{}]interface{}{"net.core.somaxconn": 1024}, map[string]string{"net.core.somaxconn": "1024"}, false},
+		"Missing value":  {[]interface{}{"net.core.somaxconn"}, nil, true},
+		"Invalid type":   {42, nil, true},
+	}
+
+	for name, test := range testCases {
+		t.Log("Test case:", name)
+		sysctls, err := loadSysctls(test.rawSysctls)
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("Expected an error, got %v", sysctls)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(sysctls, test.expected) {
+			t.Errorf("Expected %v, got %v", test.expected, sysctls)
+		}
+	}
+}
+
+func TestLoadDependsOn(t *testing.T) {
+	testCases := map[string]struct {
+		rawDependsOn interface{}
+		expected     []kobject.ServiceDependency
+		expectErr    bool
+	}{
+		"List": {
+			[]interface{}{"db", "cache_1"},
+			[]kobject.ServiceDependency{{Service: "cache-1", Condition: kobject.ServiceStarted}, {Service: "db", Condition: kobject.ServiceStarted}},
+			false,
+		},
+		"Conditions": {
+			map[interface{}]interface{}{"db": map[interface{}]interface{}{"condition": "service_healthy"}, "cache": map[interface{}]interface{}{}},
+			[]kobject.ServiceDependency{{Service: "cache", Condition: kobject.ServiceStarted}, {Service: "db", Condition: kobject.ServiceHealthy}},
+			false,
+		},
+		"Merged files": {
+			[]interface{}{"cache", map[string]interface{}{"db": map[string]interface{}{"condition": "service_healthy"}}},
+			[]kobject.ServiceDependency{{Service: "cache", Condition: kobject.ServiceStarted}, {Service: "db", Condition: kobject.ServiceHealthy}},
+			false,
+		},
+		"Unsupported condition": {map[string]interface{}{"db": map[string]interface{}{"condition": "service_completed_successfully"}}, nil, true},
+		"Invalid type":          {42, nil, true},
+	}
+
+	for name, test := range testCases {
+		t.Log("Test case:", name)
+		dependsOn, err := loadDependsOn(test.rawDependsOn)
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("Expected an error, got %v", dependsOn)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(dependsOn, test.expected) {
+			t.Errorf("Expected %#v, got %#v", test.expected, dependsOn)
+		}
+	}
+}
+
+func TestLoadLinks(t *testing.T) {
+	links, err := loadLinks("web", "links", []string{"db_1:Database", "cache", "db:1db"}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []kobject.Link{
+		{Service: "db-1", Alias: "database"},
+		{Service: "cache", Alias: "cache"},
+	}
+	if !reflect.DeepEqual(links, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, links)
+	}
+
+	labels := map[string]string{"kompose.service.external_link.ldap": "ldap.example.com"}
+	links, err = loadLinks("web", "external_links", []string{"legacy_redis_1:redis", "ldap"}, labels)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected = []kobject.Link{
+		{Service: "legacy_redis_1", Alias: "redis"},
+		{Service: "ldap", Alias: "ldap", ExternalName: "ldap.example.com"},
+	}
+	if !reflect.DeepEqual(links, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, links)
+	}
+
+	labels["kompose.service.external_link.ldap"] = "ldap_server"
+	if _, err := loadLinks("web", "external_links", []string{"ldap"}, labels); err == nil {
+		t.Errorf("Expected an error for an invalid DNS name")
+	}
+}
+
+func TestLoadStopSignal(t *testing.T) {
+	testCases := map[string]struct {
+		value    string
+		expected string
+	}{
+		"name":           {"SIGQUIT", "QUIT"},
+		"short name":     {"usr1", "USR1"},
+		"number":         {"10", "USR1"},
+		"unknown name":   {"SIGFOO", ""},
+		"unknown number": {"64", ""},
+		"empty":          {"", ""},
+	}
+	for name, test := range testCases {
+		t.Log("Test case:", name)
+		if signal := loadStopSignal("web", test.value); signal != test.expected {
+			t.Errorf("Expected %q, got %q", test.expected, signal)
+		}
+	}
+}
+
+func TestLoadDNSLabel(t *testing.T) {
+	testCases := map[string]struct {
+		value    string
+		expected string
+	}{
+		"label":           {"kafka-1", "kafka-1"},
+		"uppercase label": {"Kafka", "kafka"},
+		"domain":          {"example.com", ""},
+		"empty":           {"", ""},
+	}
+	for name, test := range testCases {
+		t.Log("Test case:", name)
+		if label := loadDNSLabel("hostname", "kafka", test.value); label != test.expected {
+			t.Errorf("Expected %q, got %q", test.expected, label)
+		}
+	}
+}
+
 // TestUnsupportedKeys test checkUnsupportedKey function with various
 // docker-compose projects
 func TestUnsupportedKeys(t *testing.T) {
@@ -221,8 +1159,8 @@ func TestUnsupportedKeys(t *testing.T) {
 		Build: yaml.Build{
 			Context: "./build",
 		},
-		Hostname: "localhost",
-		Ports:    []string{}, // test empty array
+		CgroupParent: "kompose",
+		Ports:        []string{}, // test empty array
 		Networks: &yaml.Networks{
 			Networks: []*yaml.Network{
 				&yaml.Network{
@@ -236,8 +1174,8 @@ func TestUnsupportedKeys(t *testing.T) {
 		Build: yaml.Build{
 			Context: "./build",
 		},
-		Hostname: "localhost",
-		Ports:    []string{}, // test empty array
+		CgroupParent: "kompose",
+		Ports:        []string{}, // test empty array
 		Networks: &yaml.Networks{
 			Networks: []*yaml.Network{
 				&yaml.Network{
@@ -283,11 +1221,11 @@ func TestUnsupportedKeys(t *testing.T) {
 	}{
 		"With Networks (service and root level)": {
 			projectWithNetworks,
-			[]string{"root level networks", "root level volumes", "hostname", "networks"},
+			[]string{"cgroup_parent"},
 		},
 		"Empty Networks on Service level": {
 			projectWithEmptyNetwork,
-			[]string{"networks"},
+			[]string(nil),
 		},
 		"Default root level Network": {
 			projectWithDefaultNetwork,
@@ -305,6 +1243,35 @@ func TestUnsupportedKeys(t *testing.T) {
 
 }
 
+// TestUnsupportedKeysV3 test checkUnsupportedKeyV3 function with various
+// docker/cli configs
+func TestUnsupportedKeysV3(t *testing.T) {
+	testCases := map[string]struct {
+		config                  *types.Config
+		expectedUnsupportedKeys []string
+	}{
+		"Supported keys only": {
+			&types.Config{Services: []types.ServiceConfig{{Name: "web", Image: "nginx", Devices: []string{}}}},
+			[]string(nil),
+		},
+		"Unsupported keys": {
+			&types.Config{Services: []types.ServiceConfig{
+				{Name: "web", Image: "nginx", Devices: []string{"/dev/tty0"}, DNS: types.StringList{"10.0.0.2"}, Ipc: "host"},
+				{Name: "db", Image: "redis", Ipc: "host", CgroupParent: "m-executor-abcd", ExtraHosts: types.MappingWithColon{"partner": "203.0.113.10"}},
+			}},
+			[]string{"devices", "dns", "ipc", "cgroup_parent", "extra_hosts"},
+		},
+	}
+
+	for name, test := range testCases {
+		t.Log("Test case:", name)
+		keys := checkUnsupportedKeyV3(test.config)
+		if !reflect.DeepEqual(keys, test.expectedUnsupportedKeys) {
+			t.Errorf("ERROR: Expecting unsupported keys: ['%s']. Got: ['%s']", strings.Join(test.expectedUnsupportedKeys, "', '"), strings.Join(keys, "', '"))
+		}
+	}
+}
+
 func TestNormalizeServiceNames(t *testing.T) {
 	testCases := []struct {
 		composeServiceName    string
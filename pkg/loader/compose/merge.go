@@ -0,0 +1,326 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package compose
+
+import (
+	"fmt"
+	"strings"
+)
+
+// docker/cli can't load multiple v3 files, so they are merged before loading,
+// the way docker-compose does it.
+// See: https://docs.docker.com/compose/extends/#adding-and-overriding-configuration
+
+// service keys whose values are a map, or a list of "key=value" (or "key:value" for extra_hosts),
+// merged by key
+var mergeMappingKeys = map[string]string{
+	"environment": "=",
+	"labels":      "=",
+	"sysctls":     "=",
+	"extra_hosts": ":",
+}
+
+// service keys whose values are a list (or a single string), merged by keeping the unique items
+var mergeUniqueListKeys = map[string]bool{
+	"cap_add":        true,
+	"cap_drop":       true,
+	"configs":        true,
+	"depends_on":     true,
+	"dns":            true,
+	"dns_search":     true,
+	"env_file":       true,
+	"expose":         true,
+	"external_links": true,
+	"group_add":      true,
+	"links":          true,
+	"ports":          true,
+	"secrets":        true,
+	"security_opt":   true,
+	"tmpfs":          true,
+}
+
+// service keys whose values are a list of mounts, merged by mount path in the container
+var mergeMountKeys = map[string]bool{
+	"devices": true,
+	"volumes": true,
+}
+
+// service keys whose values are a map, merged recursively
+var mergeDictKeys = map[string]bool{
+	"build":       true,
+	"deploy":      true,
+	"healthcheck": true,
+	"logging":     true,
+	"networks":    true,
+	"ulimits":     true,
+}
+
+// mergeV3ComposeFiles merges parsed v3 compose files, a later file overrides an earlier one.
+// Services are merged key by key, the items of other top level sections are replaced by name,
+// and top level keys which aren't a section, like scalar or list extension fields, are replaced.
+func mergeV3ComposeFiles(parsedComposeFiles []map[string]interface{}) (map[string]interface{}, error) {
+	merged := map[string]interface{}{}
+	for _, parsedComposeFile := range parsedComposeFiles {
+		for key, value := range parsedComposeFile {
+			switch key {
+			case "version":
+				merged[key] = value
+			case "services":
+				services, err := mergeV3Services(merged[key], value)
+				if err != nil {
+					return nil, err
+				}
+				merged[key] = services
+			default:
+				section, ok := value.(map[string]interface{})
+				if !ok {
+					merged[key] = value
+					continue
+				}
+				mergedSection, _ := merged[key].(map[string]interface{})
+				if mergedSection == nil {
+					mergedSection = map[string]interface{}{}
+				}
+				for name, item := range section {
+					mergedSection[name] = item
+				}
+				merged[key] = mergedSection
+			}
+		}
+	}
+	return merged, nil
+}
+
+// mergeV3Services merges the services of a compose file into the services merged so far
+func mergeV3Services(base interface{}, override interface{}) (map[string]interface{}, error) {
+	overrideServices, ok := override.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid type %T for services", override)
+	}
+	merged, _ := base.(map[string]interface{})
+	if merged == nil {
+		merged = map[string]interface{}{}
+	}
+
+	for name, service := range overrideServices {
+		overrideService, ok := service.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid type %T for service %s", service, name)
+		}
+		baseService, ok := merged[name].(map[string]interface{})
+		if !ok {
+			merged[name] = overrideService
+			continue
+		}
+		mergedService, err := mergeV3Service(baseService, overrideService)
+		if err != nil {
+			return nil, fmt.Errorf("unable to merge service %s: %v", name, err)
+		}
+		merged[name] = mergedService
+	}
+	return merged, nil
+}
+
+// mergeV3Service merges two definitions of the same service, keys which can hold
+// several values are merged, the value of the other keys is replaced
+func mergeV3Service(base map[string]interface{}, override map[string]interface{}) (map[string]interface{}, error) {
+	merged := map[string]interface{}{}
+	for key, value := range base {
+		merged[key] = value
+	}
+
+	for key, value := range override {
+		baseValue, ok := merged[key]
+		if !ok {
+			merged[key] = value
+			continue
+		}
+
+		var err error
+		switch {
+		case mergeMappingKeys[key] != "":
+			merged[key], err = mergeMapping(baseValue, value, mergeMappingKeys[key])
+		case mergeUniqueListKeys[key]:
+			merged[key] = mergeUniqueList(baseValue, value)
+		case mergeMountKeys[key]:
+			merged[key] = mergeMounts(baseValue, value)
+		case mergeDictKeys[key]:
+			merged[key], err = mergeDict(key, baseValue, value)
+		default:
+			merged[key] = value
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return merged, nil
+}
+
+// toMapping converts a map, or a list of items separated by sep, to a map
+func toMapping(value interface{}, sep string) (map[string]interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return v, nil
+	case map[interface{}]interface{}:
+		// libcompose leaves the maps it doesn't parse with interface{} keys
+		mapping := map[string]interface{}{}
+		for key, item := range v {
+			mapping[fmt.Sprint(key)] = item
+		}
+		return mapping, nil
+	case []interface{}:
+		mapping := map[string]interface{}{}
+		for _, item := range v {
+			parts := strings.SplitN(fmt.Sprint(item), sep, 2)
+			if len(parts) == 1 {
+				mapping[parts[0]] = nil
+			} else {
+				mapping[parts[0]] = parts[1]
+			}
+		}
+		return mapping, nil
+	}
+	return nil, fmt.Errorf("invalid type %T, must be a map or a list", value)
+}
+
+// mergeMapping merges two mappings by key
+func mergeMapping(base interface{}, override interface{}, sep string) (map[string]interface{}, error) {
+	baseMapping, err := toMapping(base, sep)
+	if err != nil {
+		return nil, err
+	}
+	overrideMapping, err := toMapping(override, sep)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := map[string]interface{}{}
+	for key, value := range baseMapping {
+		merged[key] = value
+	}
+	for key, value := range overrideMapping {
+		merged[key] = value
+	}
+	return merged, nil
+}
+
+// concatLists converts each value to a list if it is a single value, and concatenates them
+func concatLists(values ...interface{}) []interface{} {
+	var result []interface{}
+	for _, value := range values {
+		if list, ok := value.([]interface{}); ok {
+			result = append(result, list...)
+		} else {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// mergeUniqueList appends the items of override which are not in base
+func mergeUniqueList(base interface{}, override interface{}) []interface{} {
+	var merged []interface{}
+	seen := map[string]bool{}
+	for _, item := range concatLists(base, override) {
+		key := fmt.Sprint(item)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		merged = append(merged, item)
+	}
+	return merged
+}
+
+// mountTarget returns the path in the container of a volume or a device,
+// given either in the short syntax ("source:target:mode") or in the long syntax
+func mountTarget(mount interface{}) string {
+	if m, ok := mount.(map[string]interface{}); ok {
+		return fmt.Sprint(m["target"])
+	}
+	parts := strings.Split(fmt.Sprint(mount), ":")
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return parts[1]
+}
+
+// mergeMounts merges two lists of mounts, a mount of override replaces the mount of base with the same target
+func mergeMounts(base interface{}, override interface{}) []interface{} {
+	var merged []interface{}
+	index := map[string]int{}
+	for _, mount := range concatLists(base, override) {
+		target := mountTarget(mount)
+		if i, ok := index[target]; ok {
+			merged[i] = mount
+			continue
+		}
+		index[target] = len(merged)
+		merged = append(merged, mount)
+	}
+	return merged
+}
+
+// mergeDict merges two maps recursively, the "key=value" mappings nested in them
+// (like build args or deploy labels) are merged too, while the scalars and lists
+// nested in them (like placement constraints) are replaced by the ones of override
+func mergeDict(key string, base interface{}, override interface{}) (interface{}, error) {
+	// the short syntax of these keys is converted to the map one
+	switch key {
+	case "args", "labels":
+		return mergeMapping(base, override, "=")
+	case "build":
+		if context, ok := base.(string); ok {
+			base = map[string]interface{}{"context": context}
+		}
+		if context, ok := override.(string); ok {
+			override = map[string]interface{}{"context": context}
+		}
+	case "networks":
+		if networks, ok := base.([]interface{}); ok {
+			base, _ = toMapping(networks, "=")
+		}
+		if networks, ok := override.([]interface{}); ok {
+			override, _ = toMapping(networks, "=")
+		}
+	}
+
+	baseDict, baseOk := base.(map[string]interface{})
+	overrideDict, overrideOk := override.(map[string]interface{})
+	if !baseOk || !overrideOk {
+		// scalars and lists nested in a map, like placement constraints, are replaced
+		return override, nil
+	}
+
+	merged := map[string]interface{}{}
+	for k, v := range baseDict {
+		merged[k] = v
+	}
+	for k, v := range overrideDict {
+		baseValue, ok := merged[k]
+		if !ok {
+			merged[k] = v
+			continue
+		}
+		mergedValue, err := mergeDict(k, baseValue, v)
+		if err != nil {
+			return nil, err
+		}
+		merged[k] = mergedValue
+	}
+	return merged, nil
+}
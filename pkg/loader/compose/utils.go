@@ -17,15 +17,49 @@ limitations under the License.
 package compose
 
 import (
+	"fmt"
+	"io/ioutil"
+	"net"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
+	log "github.com/Sirupsen/logrus"
+	runconfigopts "github.com/docker/docker/runconfig/opts"
 	"github.com/kubernetes/kompose/pkg/kobject"
 	"github.com/pkg/errors"
 	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/api/resource"
+	"k8s.io/kubernetes/pkg/util/validation"
 )
 
+// defaultNetwork is the network the services which don't declare networks are attached to
+const defaultNetwork = "default"
+
+// fileObjectDirs are the directories secrets and configs are mounted in when their target is not absolute
+var fileObjectDirs = map[string]string{
+	"secret": "/run/secrets",
+	"config": "/",
+}
+
+// namedVolume is the configuration of a top level volume, which is converted
+// to a PersistentVolumeClaim shared by all the services mounting the volume
+type namedVolume struct {
+	claimName    string
+	size         string
+	storageClass string
+	accessMode   string
+	external     bool
+}
+
+// volumeLabelPrefix is the prefix of the top level volume labels that configure its PersistentVolumeClaim.
+// driver_opts can be used too, with the same keys without the prefix.
+const volumeLabelPrefix = "kompose.volume."
+
 // load environment variables from compose file
 func loadEnvVars(envars []string) []kobject.EnvVar {
 	envs := []kobject.EnvVar{}
@@ -69,6 +103,54 @@ func loadEnvVars(envars []string) []kobject.EnvVar {
 	return envs
 }
 
+// invalidConfigMapNameChars matches the characters of an env_file path which can't be used in a ConfigMap name
+var invalidConfigMapNameChars = regexp.MustCompile("[^a-z0-9]+")
+
+// loadEnvFiles reads the env_file entries of a service, relative to the compose file directory.
+// Each file gets its own ConfigMap, named after the service and the file path. Paths which sanitize
+// to the same name ("common.env", "../common_env") are told apart by the index of their entry.
+func loadEnvFiles(svcName string, envFiles []string, composeFileDir string) ([]kobject.EnvFile, error) {
+	var komposeEnvFiles []kobject.EnvFile
+	names := map[string]bool{}
+	for i, envFile := range envFiles {
+		filePath := envFile
+		if !filepath.IsAbs(filePath) {
+			filePath = filepath.Join(composeFileDir, filePath)
+		}
+
+		// lines are already in the KEY=VALUE form, variables without value are looked up in os env
+		lines, err := runconfigopts.ParseEnvFile(filePath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to read env_file %q", envFile)
+		}
+		var envs []kobject.EnvVar
+		for _, line := range lines {
+			values := strings.SplitN(line, "=", 2)
+			envs = append(envs, kobject.EnvVar{
+				Name:  values[0],
+				Value: values[1],
+			})
+		}
+
+		fileName := strings.Trim(invalidConfigMapNameChars.ReplaceAllString(strings.ToLower(envFile), "-"), "-")
+		name := svcName + "-" + fileName
+		for index := i + 1; names[name]; index++ {
+			name = fmt.Sprintf("%s-%s-%d", svcName, fileName, index)
+		}
+		if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid ConfigMap name %q for env_file %q: %s", name, envFile, strings.Join(errs, ", "))
+		}
+		names[name] = true
+
+		komposeEnvFiles = append(komposeEnvFiles, kobject.EnvFile{
+			Name:        name,
+			Path:        envFile,
+			Environment: envs,
+		})
+	}
+	return komposeEnvFiles, nil
+}
+
 // getComposeFileDir returns compose file directory
 // Assume all the docker-compose files are in the same directory
 // TODO: fix (check if file exists)
@@ -100,3 +182,522 @@ func handleServiceType(ServiceType string) (string, error) {
 func normalizeServiceNames(svcName string) string {
 	return strings.Replace(svcName, "_", "-", -1)
 }
+
+// loadNamedVolume converts the configuration of a top level volume, labels override driver_opts.
+// An external volume references an existing PersistentVolumeClaim named after the volume, or after external.name.
+func loadNamedVolume(name string, driverOpts map[string]string, labels map[string]string, external bool, externalName string) (namedVolume, error) {
+	volume := namedVolume{
+		claimName: normalizeServiceNames(name),
+		external:  external,
+	}
+	if external {
+		if externalName != "" {
+			volume.claimName = normalizeServiceNames(externalName)
+		}
+		return volume, nil
+	}
+
+	option := func(key string) string {
+		if value, ok := labels[volumeLabelPrefix+key]; ok {
+			return value
+		}
+		return driverOpts[key]
+	}
+
+	if size := option("size"); size != "" {
+		if _, err := resource.ParseQuantity(size); err != nil {
+			return namedVolume{}, errors.Errorf("invalid size %q for volume %q", size, name)
+		}
+		volume.size = size
+	}
+	volume.storageClass = option("storage-class")
+	if accessMode := option("access-mode"); accessMode != "" {
+		switch api.PersistentVolumeAccessMode(accessMode) {
+		case api.ReadWriteOnce, api.ReadOnlyMany, api.ReadWriteMany:
+			volume.accessMode = accessMode
+		default:
+			return namedVolume{}, errors.Errorf("invalid access mode %q for volume %q, must be one of %s, %s or %s", accessMode, name, api.ReadWriteOnce, api.ReadOnlyMany, api.ReadWriteMany)
+		}
+	}
+	return volume, nil
+}
+
+// shareNamedVolumes makes all the services mounting a named volume use the same PersistentVolumeClaim,
+// configured by the top level volume when it is declared. The claim is created with the first
+// service mounting it (sorted by name), the other services reference it like volumes_from does.
+// Unless it is set, the access mode is ReadOnlyMany if every mount is read-only, ReadWriteOnce otherwise.
+func shareNamedVolumes(komposeObject *kobject.KomposeObject, namedVolumes map[string]namedVolume) {
+	var names []string
+	for name := range komposeObject.ServiceConfigs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	owners := make(map[string]string)
+	readOnly := make(map[string]bool)
+	for _, name := range names {
+		for _, volume := range komposeObject.ServiceConfigs[name].Volumes {
+			if volume.VolumeName == "" {
+				continue
+			}
+			if _, ok := owners[volume.VolumeName]; !ok && volume.VFrom == "" {
+				owners[volume.VolumeName] = name
+			}
+			if ro, ok := readOnly[volume.VolumeName]; ok {
+				readOnly[volume.VolumeName] = ro && volume.Mode == "ro"
+			} else {
+				readOnly[volume.VolumeName] = volume.Mode == "ro"
+			}
+		}
+	}
+
+	for _, name := range names {
+		service := komposeObject.ServiceConfigs[name]
+		for i, volume := range service.Volumes {
+			if volume.VolumeName == "" {
+				continue
+			}
+			if owner := owners[volume.VolumeName]; volume.VFrom == "" && owner != name {
+				volume.VFrom = owner
+			}
+			if config, ok := namedVolumes[volume.VolumeName]; ok {
+				volume.PVCName = config.claimName
+				volume.PVCSize = config.size
+				volume.StorageClass = config.storageClass
+				volume.AccessMode = config.accessMode
+				volume.External = config.external
+			}
+			if volume.AccessMode == "" {
+				volume.AccessMode = string(api.ReadWriteOnce)
+				if readOnly[volume.VolumeName] {
+					volume.AccessMode = string(api.ReadOnlyMany)
+				}
+			}
+			service.Volumes[i] = volume
+		}
+		komposeObject.ServiceConfigs[name] = service
+	}
+}
+
+// loadServiceNetworks returns the sorted networks a service is attached to. A service which
+// doesn't declare networks is attached to the default network, unless it sets network_mode.
+func loadServiceNetworks(names []string, networkMode string) []string {
+	if networkMode != "" {
+		return nil
+	}
+	if len(names) == 0 {
+		return []string{defaultNetwork}
+	}
+	var networks []string
+	for _, name := range names {
+		networks = append(networks, normalizeServiceNames(name))
+	}
+	sort.Strings(networks)
+	return networks
+}
+
+// signals are the names of the Linux signals, by number
+var signals = []string{"", "HUP", "INT", "QUIT", "ILL", "TRAP", "ABRT", "BUS", "FPE", "KILL", "USR1", "SEGV", "USR2",
+	"PIPE", "ALRM", "TERM", "STKFLT", "CHLD", "CONT", "STOP", "TSTP", "TTIN", "TTOU", "URG", "XCPU", "XFSZ", "VTALRM",
+	"PROF", "WINCH", "IO", "PWR", "SYS"}
+
+// loadStopSignal returns the name of the stop_signal of a service, without the SIG prefix.
+// Like Docker, the signal can be a name ("SIGQUIT" or "QUIT") or a number, an unknown signal is reported
+func loadStopSignal(name string, stopSignal string) string {
+	if stopSignal == "" {
+		return ""
+	}
+	if number, err := strconv.Atoi(stopSignal); err == nil {
+		if number > 0 && number < len(signals) {
+			return signals[number]
+		}
+	} else {
+		signal := strings.TrimPrefix(strings.ToUpper(stopSignal), "SIG")
+		for _, s := range signals[1:] {
+			if s == signal {
+				return signal
+			}
+		}
+	}
+	log.Warningf("Unsupported stop_signal %q of service %q - ignoring, it is not a Linux signal", stopSignal, name)
+	return ""
+}
+
+// loadPortRange parses a port ("80") or a range of ports ("8000-8010"), and returns the first and last ports
+func loadPortRange(portRange string) (int, int, error) {
+	parts := strings.SplitN(portRange, "-", 2)
+	start, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	end := start
+	if len(parts) == 2 {
+		end, err = strconv.Atoi(parts[1])
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	if start < 1 || end > 65535 || end < start {
+		return 0, 0, fmt.Errorf("invalid port range %q", portRange)
+	}
+	return start, end, nil
+}
+
+// loadPortSpec parses a port in the short syntax ("[[ip:]host_port:]container_port[/protocol]"),
+// in which the ports can be ranges ("8000-8010:8000-8010"), expanded into a port each.
+// Like Docker, a range of host ports for a single container port is a range to pick the host port from,
+// the first host port is used.
+func loadPortSpec(port string) ([]kobject.Ports, error) {
+	// Get the TCP / UDP protocol. Checks to see if it splits in 2 with '/' character.
+	// ex. 15000:15000/tcp
+	// else, set a default protocol of using TCP
+	proto := api.ProtocolTCP
+	protocolCheck := strings.Split(port, "/")
+	if len(protocolCheck) == 2 {
+		if strings.EqualFold("tcp", protocolCheck[1]) {
+			proto = api.ProtocolTCP
+		} else if strings.EqualFold("udp", protocolCheck[1]) {
+			proto = api.ProtocolUDP
+		} else {
+			return nil, fmt.Errorf("invalid protocol %q", protocolCheck[1])
+		}
+	}
+
+	// Split up the ports / IP without the "/tcp" or "/udp" appended to it
+	var hostIP, hostPorts, containerPorts string
+	justPorts := strings.Split(protocolCheck[0], ":")
+	switch len(justPorts) {
+	case 3:
+		// ex. 127.0.0.1:80:80
+		hostIP, hostPorts, containerPorts = justPorts[0], justPorts[1], justPorts[2]
+		ip := net.ParseIP(hostIP)
+		if ip.To4() == nil && ip.To16() == nil {
+			return nil, fmt.Errorf("%q contains an invalid IPv4 or IPv6 IP address", port)
+		}
+	case 2:
+		// ex. 80:80
+		hostPorts, containerPorts = justPorts[0], justPorts[1]
+	case 1:
+		// ex. 80
+		containerPorts = justPorts[0]
+	default:
+		return nil, fmt.Errorf("invalid port %q valid example: 127.0.0.1:80:80", port)
+	}
+
+	containerStart, containerEnd, err := loadPortRange(containerPorts)
+	if err != nil {
+		return nil, fmt.Errorf("invalid container port %q valid example: 80 or 8000-8010", port)
+	}
+	var hostStart, hostEnd int
+	// the host port can be omitted with an IP (127.0.0.1::80)
+	if hostPorts != "" {
+		hostStart, hostEnd, err = loadPortRange(hostPorts)
+		if err != nil {
+			return nil, fmt.Errorf("invalid host port %q valid example: 80:80 or 8000-8010:8000-8010", port)
+		}
+		if hostEnd-hostStart != containerEnd-containerStart {
+			if containerStart != containerEnd {
+				return nil, fmt.Errorf("invalid port %q, the host and container port ranges must have the same size", port)
+			}
+			log.Warningf("Unsupported host port range of %q - using host port %d", port, hostStart)
+			hostEnd = hostStart
+		}
+	}
+
+	var ports []kobject.Ports
+	for i := 0; i <= containerEnd-containerStart; i++ {
+		hostPort := 0
+		if hostPorts != "" {
+			hostPort = hostStart + i
+		}
+		ports = append(ports, kobject.Ports{
+			HostPort:      int32(hostPort),
+			ContainerPort: int32(containerStart + i),
+			HostIP:        hostIP,
+			Protocol:      proto,
+		})
+	}
+	return ports, nil
+}
+
+// loadNetworkMode returns the service whose pod a service joins with network_mode: "service:<name>",
+// the other network modes are reported
+func loadNetworkMode(name string, networkMode string) string {
+	if networkMode == "" {
+		return ""
+	}
+	if strings.HasPrefix(networkMode, "service:") {
+		return normalizeServiceNames(strings.TrimPrefix(networkMode, "service:"))
+	}
+	log.Warningf("Unsupported network_mode %q of service %q - ignoring, only \"service:<name>\" is supported, it makes the service a container of the pod of <name>", networkMode, name)
+	return ""
+}
+
+// groupPodServices moves the services joining the pod of another service, with network_mode: "service:<name>"
+// or the kompose.pod.group label, to the containers of that service. A service can join the pod of a service
+// which joins another pod, and the dependencies and links on the services of a pod are on the pod.
+func groupPodServices(komposeObject *kobject.KomposeObject) error {
+	var names []string
+	for name, service := range komposeObject.ServiceConfigs {
+		if service.PodGroup != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	pods := make(map[string]string)
+	for _, name := range names {
+		pod := name
+		seen := map[string]bool{name: true}
+		for komposeObject.ServiceConfigs[pod].PodGroup != "" {
+			next := komposeObject.ServiceConfigs[pod].PodGroup
+			if _, ok := komposeObject.ServiceConfigs[next]; !ok {
+				return fmt.Errorf("service %q joins the pod of service %q, which doesn't exist", pod, next)
+			}
+			if seen[next] {
+				return fmt.Errorf("service %q joins the pod of service %q, which joins its own pod", pod, next)
+			}
+			seen[next] = true
+			pod = next
+		}
+		pods[name] = pod
+	}
+
+	for _, name := range names {
+		service := komposeObject.ServiceConfigs[name]
+		if service.ContainerName == "" {
+			service.ContainerName = name
+		}
+		pod := komposeObject.ServiceConfigs[pods[name]]
+		pod.Containers = append(pod.Containers, service)
+		pod.DependsOn = append(pod.DependsOn, service.DependsOn...)
+		// the init containers waiting for the dependencies are containers of the pod
+		pod.WaitForDependencies = pod.WaitForDependencies || service.WaitForDependencies
+		komposeObject.ServiceConfigs[pods[name]] = pod
+	}
+	for _, name := range names {
+		delete(komposeObject.ServiceConfigs, name)
+	}
+
+	// the dependencies on the services joining a pod are dependencies on the pod
+	podDependsOn := func(name string, dependencies []kobject.ServiceDependency) []kobject.ServiceDependency {
+		var dependsOn []kobject.ServiceDependency
+		seen := make(map[string]bool)
+		for _, dependency := range dependencies {
+			if pod, ok := pods[dependency.Service]; ok {
+				dependency.Service = pod
+			}
+			if dependency.Service == name || seen[dependency.Service] {
+				continue
+			}
+			seen[dependency.Service] = true
+			dependsOn = append(dependsOn, dependency)
+		}
+		return dependsOn
+	}
+	for name, service := range komposeObject.ServiceConfigs {
+		service.DependsOn = podDependsOn(name, service.DependsOn)
+		for i, container := range service.Containers {
+			service.Containers[i].DependsOn = podDependsOn(name, container.DependsOn)
+		}
+		for i, link := range service.Links {
+			if pod, ok := pods[link.Service]; ok {
+				service.Links[i].Service = pod
+			}
+		}
+		komposeObject.ServiceConfigs[name] = service
+	}
+	return nil
+}
+
+// loadDependsOn converts a raw depends_on key, given either as a list of services or as a map of
+// services to their condition, to the dependencies of a service sorted by name.
+// The merged key of several files can be a list of both.
+func loadDependsOn(rawDependsOn interface{}) ([]kobject.ServiceDependency, error) {
+	conditions := make(map[string]string)
+	var load func(value interface{}) error
+	load = func(value interface{}) error {
+		switch v := value.(type) {
+		case string:
+			conditions[normalizeServiceNames(v)] = kobject.ServiceStarted
+		case []interface{}:
+			for _, item := range v {
+				if err := load(item); err != nil {
+					return err
+				}
+			}
+		case map[string]interface{}, map[interface{}]interface{}:
+			dependencies := make(map[string]interface{})
+			if m, ok := v.(map[interface{}]interface{}); ok {
+				for service, dependency := range m {
+					dependencies[fmt.Sprint(service)] = dependency
+				}
+			} else {
+				dependencies = v.(map[string]interface{})
+			}
+			for service, dependency := range dependencies {
+				condition := kobject.ServiceStarted
+				if rawCondition, ok := lookupKey(dependency, []string{"condition"}); ok {
+					condition = fmt.Sprint(rawCondition)
+				}
+				if condition != kobject.ServiceStarted && condition != kobject.ServiceHealthy {
+					return fmt.Errorf("unsupported condition %q of dependency %q", condition, service)
+				}
+				conditions[normalizeServiceNames(service)] = condition
+			}
+		default:
+			return fmt.Errorf("invalid type %T for depends_on", value)
+		}
+		return nil
+	}
+	if err := load(rawDependsOn); err != nil {
+		return nil, err
+	}
+
+	var services []string
+	for service := range conditions {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+	var dependsOn []kobject.ServiceDependency
+	for _, service := range services {
+		dependsOn = append(dependsOn, kobject.ServiceDependency{Service: service, Condition: conditions[service]})
+	}
+	return dependsOn, nil
+}
+
+// loadDNSLabel loads hostname or domainname, which become the hostname and the subdomain
+// of the pods, and must be DNS labels in Kubernetes
+func loadDNSLabel(key string, name string, value string) string {
+	label := strings.ToLower(value)
+	if label != "" && len(validation.IsDNS1123Label(label)) > 0 {
+		log.Warningf("Unsupported %s %q of service %q - ignoring, it must be a DNS label (at most 63 lowercase alphanumeric characters or '-')", key, value, name)
+		return ""
+	}
+	return label
+}
+
+// ExternalLinkLabelPrefix is the prefix of the labels setting the DNS name of the external_links, by alias
+const ExternalLinkLabelPrefix = "kompose.service.external_link."
+
+// loadLinks loads links or external_links ("name" or "name:alias"), the aliases are the names of the Services
+// making the linked service or container reachable, an alias which can't be a Service name is reported and ignored.
+// The DNS name of an external container is set with a label named after the alias, which is validated.
+func loadLinks(name string, key string, links []string, labels map[string]string) ([]kobject.Link, error) {
+	var result []kobject.Link
+	for _, link := range links {
+		parts := strings.SplitN(link, ":", 2)
+		alias := parts[0]
+		if len(parts) == 2 {
+			alias = parts[1]
+		}
+		serviceAlias := strings.ToLower(normalizeServiceNames(alias))
+		if len(validation.IsDNS1035Label(serviceAlias)) > 0 {
+			log.Warningf("Unsupported alias %q of %s of service %q - ignoring, it must be a DNS label starting with a letter", alias, key, name)
+			continue
+		}
+
+		if key == "links" {
+			result = append(result, kobject.Link{Service: normalizeServiceNames(parts[0]), Alias: serviceAlias})
+			continue
+		}
+		externalName, ok := labels[ExternalLinkLabelPrefix+alias]
+		if !ok {
+			externalName = labels[ExternalLinkLabelPrefix+serviceAlias]
+		}
+		if externalName != "" && len(validation.IsDNS1123Subdomain(externalName)) > 0 {
+			return nil, fmt.Errorf("invalid DNS name %q in %s%s label", externalName, ExternalLinkLabelPrefix, alias)
+		}
+		result = append(result, kobject.Link{Service: parts[0], Alias: serviceAlias, ExternalName: externalName})
+	}
+	return result, nil
+}
+
+// handleBoolLabel parses a true / false label, like kompose.service.wait_for_dependencies
+func handleBoolLabel(key string, value string) (bool, error) {
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, errors.Wrapf(err, "invalid value %q of %s label", value, key)
+	}
+	return b, nil
+}
+
+// placementConstraintRegexp matches a deploy.placement constraint, like "node.labels.zone == east"
+var placementConstraintRegexp = regexp.MustCompile(`^\s*([^=!\s]+)\s*(==|!=)\s*(\S+)\s*$`)
+
+// loadPlacement loads the deploy.placement constraints and the attributes of the spread preferences
+// of a service, the invalid constraints are ignored with a warning
+func loadPlacement(name string, constraints []string, spreads []string) kobject.Placement {
+	placement := kobject.Placement{Preferences: spreads}
+	for _, constraint := range constraints {
+		match := placementConstraintRegexp.FindStringSubmatch(constraint)
+		if match == nil {
+			log.Warningf("Invalid placement constraint %q of service %q - ignoring, it must be \"attribute == value\" or \"attribute != value\"", constraint, name)
+			continue
+		}
+		placement.Constraints = append(placement.Constraints, kobject.PlacementConstraint{Key: match[1], Operator: match[2], Value: match[3]})
+	}
+	return placement
+}
+
+// loadSysctls loads sysctls, given either as a map or as a list of "name=value"
+func loadSysctls(raw interface{}) (map[string]string, error) {
+	mapping, err := toMapping(raw, "=")
+	if err != nil {
+		return nil, err
+	}
+
+	sysctls := map[string]string{}
+	for name, value := range mapping {
+		if value == nil {
+			return nil, fmt.Errorf("sysctl %q has no value", name)
+		}
+		sysctls[name] = fmt.Sprint(value)
+	}
+	return sysctls, nil
+}
+
+// loadFileObject loads a top level secret or config, whose content is read from file unless it is external.
+// kind is either "secret" or "config". An external one refers to an existing object, named externalName.
+// A relative file is resolved against composeFileDir.
+func loadFileObject(kind string, name string, file string, external bool, externalName string, composeFileDir string) (kobject.FileObject, error) {
+	if external {
+		return kobject.FileObject{Name: normalizeServiceNames(externalName), External: true}, nil
+	}
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(composeFileDir, file)
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return kobject.FileObject{}, errors.Wrapf(err, "unable to read the file of %s %q", kind, name)
+	}
+	return kobject.FileObject{Name: normalizeServiceNames(name), Data: data}, nil
+}
+
+// loadFileReference loads the reference of a service to a top level secret or config, depending on kind.
+// The target defaults to the name of the secret or config, and a relative target is relative to the
+// directory of kind in fileObjectDirs.
+func loadFileReference(kind string, source string, target string, uid string, gid string, mode *uint32, objects map[string]kobject.FileObject) (kobject.FileReference, error) {
+	object, ok := objects[source]
+	if !ok {
+		return kobject.FileReference{}, fmt.Errorf("undefined %s %q", kind, source)
+	}
+	if target == "" {
+		target = source
+	}
+	if !path.IsAbs(target) {
+		target = path.Join(fileObjectDirs[kind], target)
+	}
+	reference := kobject.FileReference{
+		Source: source,
+		Name:   object.Name,
+		Target: target,
+		UID:    uid,
+		GID:    gid,
+	}
+	if mode != nil {
+		m := int32(*mode)
+		reference.Mode = &m
+	}
+	return reference, nil
+}
@@ -18,31 +18,48 @@ package compose
 
 import (
 	"fmt"
-	"net"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 
-	"k8s.io/kubernetes/pkg/api"
-
 	log "github.com/Sirupsen/logrus"
+	"github.com/docker/cli/cli/compose/types"
 	"github.com/docker/libcompose/config"
 	"github.com/docker/libcompose/lookup"
 	"github.com/docker/libcompose/project"
 	"github.com/kubernetes/kompose/pkg/kobject"
 	"github.com/kubernetes/kompose/pkg/transformer"
 	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
 )
 
 // Parse Docker Compose with libcompose (only supports v1 and v2). Eventually we will
 // switch to using only libcompose once v3 is supported.
-func parseV1V2(files []string) (kobject.KomposeObject, error) {
+func parseV1V2(files []string, version composeVersion) (kobject.KomposeObject, error) {
 
 	// Gather the appropriate context for parsing
 	context := &project.Context{}
 	context.ComposeFiles = files
 
+	// libcompose only knows version 2.0, so the files of later 2.x versions
+	// are given to it as version 2.
+	// The top level keys that it doesn't keep are read here.
+	topLevelKeys := v2TopLevelKeys{
+		volumeLabels:     make(map[string]map[string]string),
+		internalNetworks: make(map[string]bool),
+	}
+	if version.major == 2 {
+		for _, file := range files {
+			composeBytes, err := loadV2ComposeBytes(file, version, topLevelKeys)
+			if err != nil {
+				return kobject.KomposeObject{}, err
+			}
+			context.ComposeBytes = append(context.ComposeBytes, composeBytes)
+		}
+	}
+
 	if context.ResourceLookup == nil {
 		context.ResourceLookup = &lookup.FileResourceLookup{}
 	}
@@ -62,8 +79,17 @@ func parseV1V2(files []string) (kobject.KomposeObject, error) {
 		}
 	}
 
+	// Keys that libcompose doesn't know about are taken out of every service
+	// before it is validated, and are handled by kompose itself
+	extraKeys := make(map[string]config.RawService)
+	parseOptions := &config.ParseOptions{
+		Interpolate: true,
+		Validate:    true,
+		Preprocess:  extractExtraKeys(extraKeys, version),
+	}
+
 	// Load the context and let's start parsing
-	composeObject := project.NewProject(context, nil, nil)
+	composeObject := project.NewProject(context, nil, parseOptions)
 	err := composeObject.Parse()
 	if err != nil {
 		return kobject.KomposeObject{}, errors.Wrap(err, "composeObject.Parse() failed, Failed to load compose file")
@@ -75,7 +101,7 @@ func parseV1V2(files []string) (kobject.KomposeObject, error) {
 	}
 
 	// Map the parsed struct to a struct we understand (kobject)
-	komposeObject, err := libComposeToKomposeMapping(composeObject)
+	komposeObject, err := libComposeToKomposeMapping(composeObject, extraKeys, topLevelKeys)
 	if err != nil {
 		return kobject.KomposeObject{}, err
 	}
@@ -83,109 +109,259 @@ func parseV1V2(files []string) (kobject.KomposeObject, error) {
 	return komposeObject, nil
 }
 
-// Load ports from compose file
-func loadPorts(composePorts []string) ([]kobject.Ports, error) {
-	ports := []kobject.Ports{}
-	character := ":"
-
-	// For each port listed
-	for _, port := range composePorts {
+// extraServiceKeys are the service keys that kompose parses itself, either because
+// libcompose can't parse them (Compose 2.1+, like healthcheck, sysctls or the conditions of depends_on),
+// or because libcompose would merge them into other keys (env_file is merged into environment)
+var extraServiceKeys = []string{
+	"healthcheck",
+	"env_file",
+	"depends_on",
+	"sysctls",
+}
 
-		// Get the TCP / UDP protocol. Checks to see if it splits in 2 with '/' character.
-		// ex. 15000:15000/tcp
-		// else, set a default protocol of using TCP
-		proto := api.ProtocolTCP
-		protocolCheck := strings.Split(port, "/")
-		if len(protocolCheck) == 2 {
-			if strings.EqualFold("tcp", protocolCheck[1]) {
-				proto = api.ProtocolTCP
-			} else if strings.EqualFold("udp", protocolCheck[1]) {
-				proto = api.ProtocolUDP
-			} else {
-				return nil, fmt.Errorf("invalid protocol %q", protocolCheck[1])
+// extractExtraKeys returns a libcompose Preprocess function that moves extraServiceKeys
+// out of the raw services into extraKeys. Files are processed in order, so
+// a key from a later file overrides the same key from an earlier one, except
+// depends_on and sysctls which are merged like docker-compose does.
+// The keys which are too recent for the declared version are rejected, and the other
+// keys that libcompose can't parse are removed.
+func extractExtraKeys(extraKeys map[string]config.RawService, version composeVersion) func(config.RawServiceMap) (config.RawServiceMap, error) {
+	warned := make(map[string]bool)
+	return func(rawServices config.RawServiceMap) (config.RawServiceMap, error) {
+		for name, rawService := range rawServices {
+			if err := checkServiceKeyVersions(name, map[string]interface{}(rawService), version); err != nil {
+				return nil, err
 			}
+			for _, key := range extraServiceKeys {
+				value, ok := rawService[key]
+				if !ok {
+					continue
+				}
+				if extraKeys[name] == nil {
+					extraKeys[name] = config.RawService{}
+				}
+				if previous, ok := extraKeys[name][key]; ok {
+					switch key {
+					case "depends_on":
+						value = mergeUniqueList(previous, value)
+					case "sysctls":
+						merged, err := mergeMapping(previous, value, "=")
+						if err != nil {
+							return nil, errors.Wrapf(err, "unable to merge sysctls of service %s", name)
+						}
+						value = merged
+					}
+				}
+				extraKeys[name][key] = value
+				delete(rawService, key)
+			}
+			removeUnparsableServiceKeys(map[string]interface{}(rawService), version, warned)
 		}
+		return rawServices, nil
+	}
+}
 
-		// Split up the ports / IP without the "/tcp" or "/udp" appended to it
-		justPorts := strings.Split(protocolCheck[0], character)
+// v2TopLevelKeys holds the values of the top level keys that libcompose doesn't keep, by volume or network name
+type v2TopLevelKeys struct {
+	volumeLabels     map[string]map[string]string
+	internalNetworks map[string]bool
+}
 
-		if len(justPorts) == 3 {
-			// ex. 127.0.0.1:80:80
+// loadV2ComposeBytes reads a version 2.x compose file, checks its top level keys
+// and returns it as a version 2 file without extension fields
+func loadV2ComposeBytes(file string, version composeVersion, topLevelKeys v2TopLevelKeys) ([]byte, error) {
+	composeBytes, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
 
-			// Get the IP address
-			hostIP := justPorts[0]
-			ip := net.ParseIP(hostIP)
-			if ip.To4() == nil && ip.To16() == nil {
-				return nil, fmt.Errorf("%q contains an invalid IPv4 or IPv6 IP address", port)
-			}
+	var composeFile map[string]interface{}
+	if err := yaml.Unmarshal(composeBytes, &composeFile); err != nil {
+		return nil, errors.Wrapf(err, "unable to parse %s", file)
+	}
+	if err := checkTopLevelKeyVersions(composeFile, version); err != nil {
+		return nil, err
+	}
+	if err := loadV2VolumeLabels(composeFile, topLevelKeys.volumeLabels); err != nil {
+		return nil, errors.Wrapf(err, "unable to load the volumes of %s", file)
+	}
+	loadV2InternalNetworks(composeFile, topLevelKeys.internalNetworks)
+	if version.minor == 0 {
+		return composeBytes, nil
+	}
 
-			// Get the host port
-			hostPortInt, err := strconv.Atoi(justPorts[1])
-			if err != nil {
-				return nil, fmt.Errorf("invalid host port %q valid example: 127.0.0.1:80:80", port)
-			}
+	composeFile["version"] = "2"
+	removeExtensionFields(composeFile)
+	return yaml.Marshal(composeFile)
+}
 
-			// Get the container port
-			containerPortInt, err := strconv.Atoi(justPorts[2])
-			if err != nil {
-				return nil, fmt.Errorf("invalid container port %q valid example: 127.0.0.1:80:80", port)
+// loadV2VolumeLabels copies the labels of the top level volumes of a parsed compose file to volumeLabels,
+// the labels of a later file override the ones of an earlier file
+func loadV2VolumeLabels(composeFile map[string]interface{}, volumeLabels map[string]map[string]string) error {
+	volumes, ok := composeFile["volumes"].(map[interface{}]interface{})
+	if !ok {
+		return nil
+	}
+	for name, volume := range volumes {
+		volumeConfig, ok := volume.(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+		rawLabels, ok := volumeConfig["labels"]
+		if !ok {
+			continue
+		}
+		labels := make(map[string]string)
+		switch l := rawLabels.(type) {
+		case map[interface{}]interface{}:
+			for key, value := range l {
+				labels[fmt.Sprint(key)] = fmt.Sprint(value)
+			}
+		case []interface{}:
+			for _, label := range l {
+				parts := strings.SplitN(fmt.Sprint(label), "=", 2)
+				if len(parts) == 1 {
+					parts = append(parts, "")
+				}
+				labels[parts[0]] = parts[1]
 			}
+		default:
+			return fmt.Errorf("invalid type %T for labels of volume %v", rawLabels, name)
+		}
+		if volumeLabels[fmt.Sprint(name)] == nil {
+			volumeLabels[fmt.Sprint(name)] = make(map[string]string)
+		}
+		for key, value := range labels {
+			volumeLabels[fmt.Sprint(name)][key] = value
+		}
+	}
+	return nil
+}
 
-			// Convert to a kobject struct with ports as well as IP
-			ports = append(ports, kobject.Ports{
-				HostPort:      int32(hostPortInt),
-				ContainerPort: int32(containerPortInt),
-				HostIP:        hostIP,
-				Protocol:      proto,
-			})
+// loadV2InternalNetworks copies the internal key of the top level networks of a parsed compose file to internalNetworks
+func loadV2InternalNetworks(composeFile map[string]interface{}, internalNetworks map[string]bool) {
+	networks, ok := composeFile["networks"].(map[interface{}]interface{})
+	if !ok {
+		return
+	}
+	for name, network := range networks {
+		networkConfig, ok := network.(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+		internal, _ := networkConfig["internal"].(bool)
+		internalNetworks[fmt.Sprint(name)] = internal
+	}
+}
 
-		} else if len(justPorts) == 2 {
-			// ex. 80:80
+// loadHealthCheck converts a raw healthcheck key to the docker/cli struct
+func loadHealthCheck(rawHealthCheck interface{}) (types.HealthCheckConfig, error) {
+	healthCheck := types.HealthCheckConfig{}
+
+	// libcompose maps have interface{} keys, docker/cli maps have string keys
+	values := make(map[interface{}]interface{})
+	switch h := rawHealthCheck.(type) {
+	case map[interface{}]interface{}:
+		values = h
+	case map[string]interface{}:
+		for key, value := range h {
+			values[key] = value
+		}
+	default:
+		return healthCheck, fmt.Errorf("invalid type %T for healthcheck", rawHealthCheck)
+	}
 
-			// Get the host port
-			hostPortInt, err := strconv.Atoi(justPorts[0])
-			if err != nil {
-				return nil, fmt.Errorf("invalid host port %q valid example: 80:80", port)
+	for key, value := range values {
+		switch key {
+		case "test":
+			switch test := value.(type) {
+			case string:
+				healthCheck.Test = types.HealthCheckTest{"CMD-SHELL", test}
+			case []interface{}:
+				for _, t := range test {
+					healthCheck.Test = append(healthCheck.Test, fmt.Sprint(t))
+				}
+			default:
+				return healthCheck, fmt.Errorf("invalid type %T for healthcheck.test", value)
 			}
-
-			// Get the container port
-			containerPortInt, err := strconv.Atoi(justPorts[1])
+		case "interval":
+			healthCheck.Interval = fmt.Sprint(value)
+		case "timeout":
+			healthCheck.Timeout = fmt.Sprint(value)
+		case "start_period":
+			healthCheck.StartPeriod = fmt.Sprint(value)
+		case "retries":
+			retries, err := strconv.ParseUint(fmt.Sprint(value), 10, 64)
 			if err != nil {
-				return nil, fmt.Errorf("invalid container port %q valid example: 80:80", port)
+				return healthCheck, errors.Wrap(err, "invalid healthcheck.retries")
 			}
+			healthCheck.Retries = &retries
+		case "disable":
+			disable, ok := value.(bool)
+			if !ok {
+				return healthCheck, fmt.Errorf("invalid type %T for healthcheck.disable", value)
+			}
+			healthCheck.Disable = disable
+		default:
+			return healthCheck, fmt.Errorf("unsupported healthcheck key %q", key)
+		}
+	}
+	return healthCheck, nil
+}
 
-			// Convert to a kobject struct and add to the list of ports
-			ports = append(ports, kobject.Ports{
-				HostPort:      int32(hostPortInt),
-				ContainerPort: int32(containerPortInt),
-				Protocol:      proto,
-			})
-
-		} else {
-			// ex. 80
-
-			containerPortInt, err := strconv.Atoi(justPorts[0])
-			if err != nil {
-				return nil, fmt.Errorf("invalid container port %q valid example: 80", port)
+// loadEnvFilePaths converts a raw env_file key, which is either a string or a list, to a list of paths
+func loadEnvFilePaths(rawEnvFile interface{}) ([]string, error) {
+	switch envFile := rawEnvFile.(type) {
+	case string:
+		return []string{envFile}, nil
+	case []interface{}:
+		var paths []string
+		for _, path := range envFile {
+			p, ok := path.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid type %T in env_file", path)
 			}
-			ports = append(ports, kobject.Ports{
-				ContainerPort: int32(containerPortInt),
-				Protocol:      proto,
-			})
+			paths = append(paths, p)
 		}
+		return paths, nil
+	}
+	return nil, fmt.Errorf("invalid type %T for env_file", rawEnvFile)
+}
+
+// Load ports from compose file
+func loadPorts(composePorts []string) ([]kobject.Ports, error) {
+	ports := []kobject.Ports{}
 
+	// For each port listed
+	for _, port := range composePorts {
+		portSpecs, err := loadPortSpec(port)
+		if err != nil {
+			return nil, err
+		}
+		ports = append(ports, portSpecs...)
 	}
 	return ports, nil
 }
 
 // Uses libcompose's APIProject type and converts it to a Kompose object for us to understand
-func libComposeToKomposeMapping(composeObject *project.Project) (kobject.KomposeObject, error) {
+// extraKeys holds the keys of each service that libcompose couldn't parse
+func libComposeToKomposeMapping(composeObject *project.Project, extraKeys map[string]config.RawService, topLevelKeys v2TopLevelKeys) (kobject.KomposeObject, error) {
 
 	// Initialize what's going to be returned
 	komposeObject := kobject.KomposeObject{
 		ServiceConfigs: make(map[string]kobject.ServiceConfig),
+		NetworkConfigs: map[string]kobject.NetworkConfig{defaultNetwork: {}},
 		LoadedFrom:     "compose",
 	}
+	for name := range composeObject.NetworkConfigs {
+		komposeObject.NetworkConfigs[normalizeServiceNames(name)] = kobject.NetworkConfig{Internal: topLevelKeys.internalNetworks[name]}
+	}
+
+	// env_file paths are relative to the compose file directory
+	composeFileDir, err := getComposeFileDir(composeObject.Files)
+	if err != nil {
+		return kobject.KomposeObject{}, err
+	}
 
 	// Here we "clean up" the service configuration so we return something that includes
 	// all relevant information as well as avoid the unsupported keys as well.
@@ -206,6 +382,19 @@ func libComposeToKomposeMapping(composeObject *project.Project) (kobject.Kompose
 		envs := loadEnvVars(composeServiceConfig.Environment)
 		serviceConfig.Environment = envs
 
+		// load env_file
+		if rawEnvFile, ok := extraKeys[name]["env_file"]; ok {
+			envFilePaths, err := loadEnvFilePaths(rawEnvFile)
+			if err != nil {
+				return kobject.KomposeObject{}, errors.Wrap(err, "loadEnvFilePaths failed. "+name+" failed to load env_file from compose file")
+			}
+			envFiles, err := loadEnvFiles(normalizeServiceNames(name), envFilePaths, composeFileDir)
+			if err != nil {
+				return kobject.KomposeObject{}, errors.Wrap(err, "loadEnvFiles failed")
+			}
+			serviceConfig.EnvFile = envFiles
+		}
+
 		// Validate dockerfile path
 		if filepath.IsAbs(serviceConfig.Dockerfile) {
 			log.Fatalf("%q defined in service %q is an absolute path, it must be a relative path.", serviceConfig.Dockerfile, name)
@@ -220,6 +409,53 @@ func libComposeToKomposeMapping(composeObject *project.Project) (kobject.Kompose
 
 		serviceConfig.WorkingDir = composeServiceConfig.WorkingDir
 
+		// load healthcheck
+		if rawHealthCheck, ok := extraKeys[name]["healthcheck"]; ok {
+			composeHealthCheck, err := loadHealthCheck(rawHealthCheck)
+			if err != nil {
+				return kobject.KomposeObject{}, errors.Wrap(err, "loadHealthCheck failed. "+name+" failed to load healthcheck from compose file")
+			}
+			healthCheck, err := parseHealthCheck(composeHealthCheck)
+			if err != nil {
+				return kobject.KomposeObject{}, errors.Wrap(err, "Unable to parse health check")
+			}
+			serviceConfig.HealthChecks = healthCheck
+		}
+
+		// load hostname and domainname
+		serviceConfig.Hostname = loadDNSLabel("hostname", name, composeServiceConfig.Hostname)
+		serviceConfig.DomainName = loadDNSLabel("domainname", name, composeServiceConfig.DomainName)
+
+		// load sysctls and ulimits
+		if rawSysctls, ok := extraKeys[name]["sysctls"]; ok {
+			serviceConfig.Sysctls, err = loadSysctls(rawSysctls)
+			if err != nil {
+				return kobject.KomposeObject{}, errors.Wrap(err, "loadSysctls failed. "+name+" failed to load sysctls from compose file")
+			}
+		}
+		for _, ulimit := range composeServiceConfig.Ulimits.Elements {
+			serviceConfig.Ulimits = append(serviceConfig.Ulimits, kobject.Ulimit{Name: ulimit.Name, Soft: ulimit.Soft, Hard: ulimit.Hard})
+		}
+
+		// load depends_on
+		if rawDependsOn, ok := extraKeys[name]["depends_on"]; ok {
+			dependsOn, err := loadDependsOn(rawDependsOn)
+			if err != nil {
+				return kobject.KomposeObject{}, errors.Wrap(err, "loadDependsOn failed. "+name+" failed to load depends_on from compose file")
+			}
+			serviceConfig.DependsOn = dependsOn
+		}
+
+		// load links and external_links, whose aliases become Services
+		serviceConfig.Links, err = loadLinks(name, "links", composeServiceConfig.Links, composeServiceConfig.Labels)
+		if err != nil {
+			return kobject.KomposeObject{}, errors.Wrap(err, "loadLinks failed. "+name+" failed to load links from compose file")
+		}
+		serviceConfig.ExternalLinks, err = loadLinks(name, "external_links", composeServiceConfig.ExternalLinks, composeServiceConfig.Labels)
+		if err != nil {
+			return kobject.KomposeObject{}, errors.Wrap(err, "loadLinks failed. "+name+" failed to load external_links from compose file")
+		}
+
 		if composeServiceConfig.Volumes != nil {
 			for _, volume := range composeServiceConfig.Volumes.Volumes {
 				v := normalizeServiceNames(volume.String())
@@ -241,6 +477,22 @@ func libComposeToKomposeMapping(composeObject *project.Project) (kobject.Kompose
 				serviceConfig.ServiceType = serviceType
 			case "kompose.service.expose":
 				serviceConfig.ExposeService = strings.ToLower(value)
+			case "kompose.service.healthcheck.http_get_path", "kompose.service.healthcheck.http_get_port", "kompose.service.healthcheck.tcp_port":
+				if err := handleHealthCheckLabel(&serviceConfig.HealthChecks, key, value); err != nil {
+					return kobject.KomposeObject{}, errors.Wrap(err, "handleHealthCheckLabel failed")
+				}
+			case "kompose.service.wait_for_dependencies":
+				serviceConfig.WaitForDependencies, err = handleBoolLabel(key, value)
+				if err != nil {
+					return kobject.KomposeObject{}, err
+				}
+			case "kompose.service.wrap_ulimit_nofile":
+				serviceConfig.WrapUlimitNofile, err = handleBoolLabel(key, value)
+				if err != nil {
+					return kobject.KomposeObject{}, err
+				}
+			case "kompose.pod.group":
+				serviceConfig.PodGroup = normalizeServiceNames(value)
 			}
 		}
 		err = checkLabelsPorts(len(serviceConfig.Port), composeServiceConfig.Labels["kompose.service.type"], name)
@@ -253,31 +505,69 @@ func libComposeToKomposeMapping(composeObject *project.Project) (kobject.Kompose
 		serviceConfig.CPUQuota = int64(composeServiceConfig.CPUQuota)
 		serviceConfig.CapAdd = composeServiceConfig.CapAdd
 		serviceConfig.CapDrop = composeServiceConfig.CapDrop
+		serviceConfig.SecurityOpt = composeServiceConfig.SecurityOpt
+		serviceConfig.ReadOnly = composeServiceConfig.ReadOnly
+		serviceConfig.GroupAdd = composeServiceConfig.GroupAdd
 		serviceConfig.Pid = composeServiceConfig.Pid
 		serviceConfig.Expose = composeServiceConfig.Expose
 		serviceConfig.Privileged = composeServiceConfig.Privileged
 		serviceConfig.Restart = composeServiceConfig.Restart
 		serviceConfig.User = composeServiceConfig.User
 		serviceConfig.VolumesFrom = composeServiceConfig.VolumesFrom
+
+		var networks []string
+		if composeServiceConfig.Networks != nil {
+			for _, network := range composeServiceConfig.Networks.Networks {
+				networks = append(networks, network.Name)
+			}
+		}
+		serviceConfig.Network = loadServiceNetworks(networks, composeServiceConfig.NetworkMode)
+		// the kompose.pod.group label takes precedence over network_mode
+		if podGroup := loadNetworkMode(name, composeServiceConfig.NetworkMode); serviceConfig.PodGroup == "" {
+			serviceConfig.PodGroup = podGroup
+		}
 		serviceConfig.Stdin = composeServiceConfig.StdinOpen
 		serviceConfig.Tty = composeServiceConfig.Tty
 		serviceConfig.MemLimit = composeServiceConfig.MemLimit
 		serviceConfig.TmpFs = composeServiceConfig.Tmpfs
+		serviceConfig.ShmSize = composeServiceConfig.ShmSize
 		serviceConfig.StopGracePeriod = composeServiceConfig.StopGracePeriod
+		serviceConfig.StopSignal = loadStopSignal(name, composeServiceConfig.StopSignal)
 		komposeObject.ServiceConfigs[normalizeServiceNames(name)] = serviceConfig
 		if normalizeServiceNames(name) != name {
 			log.Infof("Service name in docker-compose has been changed from %q to %q", name, normalizeServiceNames(name))
 		}
 	}
 
+	// Load the top level volumes, which are shared by the services mounting them.
+	// libcompose renames the volumes mounted by the services like docker-compose does,
+	// after the project, or after the name of the external volume. Claims are named after
+	// the volume like for v3, so that they don't depend on the project directory.
+	namedVolumes := make(map[string]namedVolume)
+	for name, volumeConfig := range composeObject.VolumeConfigs {
+		if volumeConfig == nil {
+			namedVolumes[normalizeServiceNames(name)] = namedVolume{claimName: normalizeServiceNames(name)}
+			continue
+		}
+		volume, err := loadNamedVolume(name, volumeConfig.DriverOpts, topLevelKeys.volumeLabels[name], volumeConfig.External.External, volumeConfig.External.Name)
+		if err != nil {
+			return kobject.KomposeObject{}, err
+		}
+		mountedName := volume.claimName
+		if !volume.external {
+			mountedName = composeObject.Name + "_" + name
+		}
+		namedVolumes[normalizeServiceNames(mountedName)] = volume
+	}
+
 	// This will handle volume at earlier stage itself, it will resolves problems occurred due to `volumes_from` key
-	handleVolume(&komposeObject)
+	handleVolume(&komposeObject, namedVolumes)
 
 	return komposeObject, nil
 }
 
 // This function will retrieve volumes for each service, as well as it will parse volume information and store it in Volumes struct
-func handleVolume(komposeObject *kobject.KomposeObject) {
+func handleVolume(komposeObject *kobject.KomposeObject, namedVolumes map[string]namedVolume) {
 	for name, _ := range komposeObject.ServiceConfigs {
 		// retrieve volumes of service
 		vols, err := retrieveVolume(name, *komposeObject)
@@ -289,6 +579,7 @@ func handleVolume(komposeObject *kobject.KomposeObject) {
 		temp.Volumes = vols
 		komposeObject.ServiceConfigs[name] = temp
 	}
+	shareNamedVolumes(komposeObject, namedVolumes)
 }
 
 func checkLabelsPorts(noOfPort int, labels string, svcName string) error {
@@ -375,7 +666,18 @@ func ParseVols(volNames []string, svcName string) ([]kobject.Volumes, error) {
 		}
 		v.SvcName = svcName
 		v.MountPath = fmt.Sprintf("%s:%s", v.Host, v.Container)
-		v.PVCName = fmt.Sprintf("%s-claim%d", v.SvcName, i)
+		// a path on the host is a bind mount, a name or no source is a named or anonymous volume
+		if v.Host != "" {
+			v.Type = kobject.VolumeTypeBind
+		} else {
+			v.Type = kobject.VolumeTypeVolume
+		}
+		// a named volume is shared by the services mounting it, so its claim is named after the volume
+		if v.VolumeName != "" {
+			v.PVCName = v.VolumeName
+		} else {
+			v.PVCName = fmt.Sprintf("%s-claim%d", v.SvcName, i)
+		}
 		volumes = append(volumes, v)
 	}
 	return volumes, nil
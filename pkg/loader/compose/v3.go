@@ -17,14 +17,23 @@ limitations under the License.
 package compose
 
 import (
+	"fmt"
 	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/docker/go-units"
+	"github.com/docker/libcompose/config"
 	libcomposeyaml "github.com/docker/libcompose/yaml"
+	"github.com/fatih/structs"
 
 	"k8s.io/kubernetes/pkg/api"
 
+	"github.com/docker/cli/cli/compose/interpolation"
 	"github.com/docker/cli/cli/compose/loader"
 	"github.com/docker/cli/cli/compose/types"
 
@@ -51,10 +60,60 @@ func buildEnvironment() (map[string]string, error) {
 	return result, nil
 }
 
+// checkUnsupportedKeyV3 checks if the docker/cli config contains
+// keys that are not supported by this loader, like checkUnsupportedKey does for libcompose.
+// returns list of unsupported YAML keys from docker-compose
+func checkUnsupportedKeyV3(composeObject *types.Config) []string {
+
+	// list of all unsupported keys for this loader,
+	// keeping record if already saw this key in another service
+	var unsupportedKey = map[string]bool{
+		"CgroupParent":   false,
+		"CredentialSpec": false,
+		"Devices":        false,
+		"DNS":            false,
+		"DNSSearch":      false,
+		"ExtraHosts":     false,
+		"Ipc":            false,
+		"Logging":        false,
+		"MacAddress":     false,
+	}
+
+	// collect all keys found in the config
+	var keysFound []string
+
+	for i := range composeObject.Services {
+		// this reflection is used in check for empty arrays
+		val := reflect.ValueOf(composeObject.Services[i])
+		s := structs.New(composeObject.Services[i])
+
+		for _, f := range s.Fields() {
+			// Check if given key is among unsupported keys, and skip it if we already saw this key
+			if alreadySaw, ok := unsupportedKey[f.Name()]; ok && !alreadySaw {
+				if f.IsExported() && !f.IsZero() {
+					// IsZero returns false for empty slice and map ([])
+					if field := val.FieldByName(f.Name()); (field.Kind() == reflect.Slice || field.Kind() == reflect.Map) && field.Len() == 0 {
+						continue
+					}
+					// get the mapstructure tag name instead of variable name,
+					// docker/cli matches the YAML keys without a tag case-insensitively
+					yamlTagName := strings.Split(f.Tag("mapstructure"), ",")[0]
+					if yamlTagName == "" {
+						yamlTagName = strings.ToLower(f.Name())
+					}
+					keysFound = append(keysFound, yamlTagName)
+					unsupportedKey[f.Name()] = true
+				}
+			}
+		}
+	}
+	return keysFound
+}
+
 // The purpose of this is not to deploy, but to be able to parse
 // v3 of Docker Compose into a suitable format. In this case, whatever is returned
 // by docker/cli's ServiceConfig
-func parseV3(files []string) (kobject.KomposeObject, error) {
+func parseV3(files []string, version composeVersion) (kobject.KomposeObject, error) {
 
 	// In order to get V3 parsing to work, we have to go through some preliminary steps
 	// for us to hack up github.com/docker/cli in order to correctly convert to a kobject.KomposeObject
@@ -66,15 +125,25 @@ func parseV3(files []string) (kobject.KomposeObject, error) {
 	}
 
 	// Load and then parse the YAML first!
-	loadedFile, err := ioutil.ReadFile(files[0])
-	if err != nil {
-		return kobject.KomposeObject{}, err
+	// docker/cli doesn't support multiple files, so they are merged here
+	var parsedComposeFiles []map[string]interface{}
+	for _, file := range files {
+		loadedFile, err := ioutil.ReadFile(file)
+		if err != nil {
+			return kobject.KomposeObject{}, err
+		}
+
+		// Parse the Compose File
+		parsedComposeFile, err := loader.ParseYAML(loadedFile)
+		if err != nil {
+			return kobject.KomposeObject{}, errors.Wrapf(err, "unable to parse %s", file)
+		}
+		parsedComposeFiles = append(parsedComposeFiles, parsedComposeFile)
 	}
 
-	// Parse the Compose File
-	parsedComposeFile, err := loader.ParseYAML(loadedFile)
+	parsedComposeFile, err := mergeV3ComposeFiles(parsedComposeFiles)
 	if err != nil {
-		return kobject.KomposeObject{}, err
+		return kobject.KomposeObject{}, errors.Wrap(err, "unable to merge compose files")
 	}
 
 	// Config file
@@ -89,6 +158,24 @@ func parseV3(files []string) (kobject.KomposeObject, error) {
 		return kobject.KomposeObject{}, errors.Wrap(err, "cannot build environment variables")
 	}
 
+	// Keys that kompose parses itself are taken out before loading
+	extraKeys, err := extractV3ExtraKeys(parsedComposeFile, env, version)
+	if err != nil {
+		return kobject.KomposeObject{}, err
+	}
+
+	// The names of the top level entries are too recent for docker/cli too
+	topLevelNames, err := extractV3TopLevelNames(parsedComposeFile, env)
+	if err != nil {
+		return kobject.KomposeObject{}, err
+	}
+
+	// docker/cli validates the file with the schema of its version,
+	// the most recent one it knows is 3.3
+	if version.minor > parserMinorVersions[version.major] {
+		parsedComposeFile["version"] = composeVersion{major: version.major, minor: parserMinorVersions[version.major]}.String()
+	}
+
 	// Config details
 	configDetails := types.ConfigDetails{
 		WorkingDir:  workingDir,
@@ -105,11 +192,13 @@ func parseV3(files []string) (kobject.KomposeObject, error) {
 		return kobject.KomposeObject{}, err
 	}
 
-	// TODO: Check all "unsupported" keys and output details
-	// Specifically, keys such as "volumes_from" are not supported in V3.
+	noSupKeys := checkUnsupportedKeyV3(config)
+	for _, keyName := range noSupKeys {
+		log.Warningf("Unsupported %s key - ignoring", keyName)
+	}
 
 	// Finally, we convert the object from docker/cli's ServiceConfig to our appropriate one
-	komposeObject, err := dockerComposeToKomposeMapping(config)
+	komposeObject, err := dockerComposeToKomposeMapping(config, extraKeys, topLevelNames, workingDir)
 	if err != nil {
 		return kobject.KomposeObject{}, err
 	}
@@ -117,13 +206,287 @@ func parseV3(files []string) (kobject.KomposeObject, error) {
 	return komposeObject, nil
 }
 
+// v3ExtraServiceKeys are the service keys that kompose parses itself instead of docker/cli,
+// either because docker/cli doesn't keep them (build, sysctls, group_add, shm_size, the host IP of ports), because docker/cli would merge
+// them into other keys (env_file is merged into environment), or because the docker/cli
+// schemas don't know all their keys (healthcheck start_period and update_config order are from 3.4,
+// rollback_config is from 3.7, and the conditions of depends_on are only part of the
+// Compose specification). Nested keys are separated by ".".
+var v3ExtraServiceKeys = []string{
+	"env_file",
+	"build",
+	"healthcheck",
+	"depends_on",
+	"sysctls",
+	"group_add",
+	"shm_size",
+	"ports",
+	"deploy.update_config",
+	"deploy.rollback_config",
+}
+
+// extractV3ExtraKeys removes v3ExtraServiceKeys from every service of the parsed compose file
+// and returns them interpolated, by service name.
+// The keys which are too recent for the declared version are rejected, and the other
+// keys that docker/cli can't parse are removed.
+func extractV3ExtraKeys(parsedComposeFile map[string]interface{}, env map[string]string, version composeVersion) (map[string]interface{}, error) {
+	extraKeys := make(map[string]interface{})
+	if err := checkTopLevelKeyVersions(parsedComposeFile, version); err != nil {
+		return nil, err
+	}
+	removeExtensionFields(parsedComposeFile)
+
+	services, ok := parsedComposeFile["services"].(map[string]interface{})
+	if !ok {
+		return extraKeys, nil
+	}
+	warned := make(map[string]bool)
+	for name, service := range services {
+		serviceDict, ok := service.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if err := checkServiceKeyVersions(name, serviceDict, version); err != nil {
+			return nil, err
+		}
+		serviceExtraKeys := make(map[string]interface{})
+		for _, key := range v3ExtraServiceKeys {
+			path := strings.Split(key, ".")
+			if value, ok := lookupKey(serviceDict, path); ok {
+				serviceExtraKeys[key] = value
+				deleteKey(serviceDict, path)
+			}
+		}
+		if tmpfsVolumes := extractV3TmpfsVolumes(serviceDict); len(tmpfsVolumes) > 0 {
+			serviceExtraKeys["tmpfs_volumes"] = tmpfsVolumes
+		}
+		if subPaths := extractV3VolumeSubPaths(serviceDict); len(subPaths) > 0 {
+			serviceExtraKeys["volume_subpaths"] = subPaths
+		}
+		extraKeys[name] = serviceExtraKeys
+		removeUnparsableServiceKeys(serviceDict, version, warned)
+	}
+
+	lookupEnv := func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+	return interpolation.Interpolate(extraKeys, "service", lookupEnv)
+}
+
+// v3TopLevelNameSections are the top level sections whose entries can set the name of the object they become,
+// the name key is from 3.4 for volumes and from 3.5 for the others
+var v3TopLevelNameSections = []string{"volumes", "networks", "secrets", "configs"}
+
+// extractV3TopLevelNames removes the name key of the entries of v3TopLevelNameSections from the parsed
+// compose file, docker/cli doesn't know it, and returns them interpolated, by section and entry
+func extractV3TopLevelNames(parsedComposeFile map[string]interface{}, env map[string]string) (map[string]map[string]string, error) {
+	lookupEnv := func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+
+	topLevelNames := make(map[string]map[string]string)
+	for _, section := range v3TopLevelNameSections {
+		entries, _ := parsedComposeFile[section].(map[string]interface{})
+		names := make(map[string]interface{})
+		for name, entry := range entries {
+			entryDict, ok := entry.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if value, ok := entryDict["name"]; ok {
+				names[name] = map[string]interface{}{"name": value}
+				delete(entryDict, "name")
+			}
+		}
+		interpolated, err := interpolation.Interpolate(names, section, lookupEnv)
+		if err != nil {
+			return nil, err
+		}
+		topLevelNames[section] = make(map[string]string)
+		for name, entry := range interpolated {
+			topLevelNames[section][name] = fmt.Sprint(entry.(map[string]interface{})["name"])
+		}
+	}
+	return topLevelNames, nil
+}
+
+// extractV3VolumeSubPaths removes the subpath of the volumes of a service, which docker/cli doesn't know,
+// and returns them by mount path in the container
+func extractV3VolumeSubPaths(service map[string]interface{}) map[string]interface{} {
+	volumes, ok := service["volumes"].([]interface{})
+	if !ok {
+		return nil
+	}
+
+	subPaths := make(map[string]interface{})
+	for _, volume := range volumes {
+		volumeDict, ok := volume.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if options, ok := volumeDict["volume"].(map[string]interface{}); ok {
+			if subPath, ok := options["subpath"]; ok {
+				subPaths[fmt.Sprint(volumeDict["target"])] = subPath
+				delete(options, "subpath")
+			}
+		}
+	}
+	return subPaths
+}
+
+// extractV3TmpfsVolumes removes the volumes of type tmpfs from a service, docker/cli doesn't keep
+// their size and mode, and returns them in the syntax of the tmpfs key ("target:size=...,mode=...")
+func extractV3TmpfsVolumes(service map[string]interface{}) []interface{} {
+	volumes, ok := service["volumes"].([]interface{})
+	if !ok {
+		return nil
+	}
+
+	var otherVolumes, tmpfsVolumes []interface{}
+	for _, volume := range volumes {
+		volumeDict, ok := volume.(map[string]interface{})
+		if !ok || volumeDict["type"] != "tmpfs" {
+			otherVolumes = append(otherVolumes, volume)
+			continue
+		}
+		tmpfs := fmt.Sprint(volumeDict["target"])
+		if tmpfsOptions, ok := volumeDict["tmpfs"].(map[string]interface{}); ok {
+			var options []string
+			for _, option := range []string{"size", "mode"} {
+				if value, ok := tmpfsOptions[option]; ok {
+					options = append(options, fmt.Sprintf("%s=%v", option, value))
+				}
+			}
+			if len(options) > 0 {
+				tmpfs += ":" + strings.Join(options, ",")
+			}
+		}
+		tmpfsVolumes = append(tmpfsVolumes, tmpfs)
+	}
+
+	if len(otherVolumes) > 0 {
+		service["volumes"] = otherVolumes
+	} else {
+		delete(service, "volumes")
+	}
+	return tmpfsVolumes
+}
+
+// loadV3Build copies a raw build key, which is either the path of the context or a map, to serviceConfig.
+// Like libcompose does for v1 and v2, a local context is resolved against the compose file directory.
+// See: https://docs.docker.com/compose/compose-file/#build
+func loadV3Build(rawBuild interface{}, composeFileDir string, serviceConfig *kobject.ServiceConfig) error {
+	switch build := rawBuild.(type) {
+	case string:
+		serviceConfig.Build = build
+	case map[string]interface{}:
+		for key, value := range build {
+			switch key {
+			case "context":
+				serviceConfig.Build = fmt.Sprint(value)
+			case "dockerfile":
+				serviceConfig.Dockerfile = fmt.Sprint(value)
+			case "target":
+				serviceConfig.BuildTarget = fmt.Sprint(value)
+			case "args":
+				args, err := loadV3MappingOrList(value, "build.args")
+				if err != nil {
+					return err
+				}
+				serviceConfig.BuildArgs = make(map[string]*string)
+				for argName, argValue := range args {
+					// args without a value are taken from the environment
+					if argValue == nil {
+						envValue := os.Getenv(argName)
+						argValue = &envValue
+					}
+					serviceConfig.BuildArgs[argName] = argValue
+				}
+			case "labels":
+				labels, err := loadV3MappingOrList(value, "build.labels")
+				if err != nil {
+					return err
+				}
+				serviceConfig.BuildLabels = make(map[string]string)
+				for labelName, labelValue := range labels {
+					serviceConfig.BuildLabels[labelName] = ""
+					if labelValue != nil {
+						serviceConfig.BuildLabels[labelName] = *labelValue
+					}
+				}
+			case "cache_from":
+				cacheFrom, ok := value.([]interface{})
+				if !ok {
+					return fmt.Errorf("invalid type %T for build.cache_from", value)
+				}
+				for _, image := range cacheFrom {
+					serviceConfig.BuildCacheFrom = append(serviceConfig.BuildCacheFrom, fmt.Sprint(image))
+				}
+			default:
+				return fmt.Errorf("unsupported build key %q", key)
+			}
+		}
+	default:
+		return fmt.Errorf("invalid type %T for build", rawBuild)
+	}
+
+	if serviceConfig.Build == "" {
+		return errors.New("build.context is required")
+	}
+	if !config.IsValidRemote(serviceConfig.Build) && !filepath.IsAbs(serviceConfig.Build) {
+		serviceConfig.Build = filepath.Join(composeFileDir, serviceConfig.Build)
+	}
+	return nil
+}
+
+// loadV3MappingOrList converts a raw key given either as a map or as a list of "key=value" to a map.
+// The value is nil when the key has no value.
+func loadV3MappingOrList(rawValue interface{}, keyName string) (map[string]*string, error) {
+	result := make(map[string]*string)
+	switch values := rawValue.(type) {
+	case map[string]interface{}:
+		for key, value := range values {
+			if value == nil {
+				result[key] = nil
+				continue
+			}
+			v := fmt.Sprint(value)
+			result[key] = &v
+		}
+	case []interface{}:
+		for _, value := range values {
+			parts := strings.SplitN(fmt.Sprint(value), "=", 2)
+			if len(parts) == 1 {
+				result[parts[0]] = nil
+				continue
+			}
+			result[parts[0]] = &parts[1]
+		}
+	default:
+		return nil, fmt.Errorf("invalid type %T for %s", rawValue, keyName)
+	}
+	return result, nil
+}
+
 // Convert the Docker Compose v3 volumes to []string (the old way)
-// TODO: Check to see if it's a "bind" or "volume". Ignore for now.
-// TODO: Refactor it similar to loadV3Ports
+// The type is kept through the source, docker/cli makes the source of a bind mount an absolute path,
+// which ParseVols tells apart from a volume name. The tmpfs volumes were extracted before loading,
+// and the options which don't apply to Kubernetes are reported.
 // See: https://docs.docker.com/compose/compose-file/#long-syntax-2
 func loadV3Volumes(volumes []types.ServiceVolumeConfig) []string {
 	var volArray []string
 	for _, vol := range volumes {
+		if vol.Consistency != "" {
+			log.Warningf("Unsupported consistency %q of volume %s - ignoring", vol.Consistency, vol.Target)
+		}
+		if vol.Bind != nil && vol.Bind.Propagation != "" {
+			log.Warningf("Unsupported bind propagation %q of volume %s - ignoring", vol.Bind.Propagation, vol.Target)
+		}
+		if vol.Volume != nil && vol.Volume.NoCopy {
+			log.Warningf("Unsupported nocopy of volume %s - ignoring", vol.Target)
+		}
 
 		// There will *always* be Source when parsing
 		v := normalizeServiceNames(vol.Source)
@@ -146,15 +509,25 @@ func loadV3Ports(ports []types.ServicePortConfig) []kobject.Ports {
 	komposePorts := []kobject.Ports{}
 
 	for _, port := range ports {
+		protocol := api.Protocol(strings.ToUpper(string(port.Protocol)))
+		if protocol == "" {
+			protocol = api.ProtocolTCP
+		}
+		// the ports published in host mode are only reachable on the node running the container
+		mode := ""
+		if port.Mode == kobject.PortModeHost {
+			mode = kobject.PortModeHost
+		}
 
 		// Convert to a kobject struct with ports
-		// NOTE: V3 doesn't use IP (they utilize Swarm instead for host-networking).
+		// NOTE: The long syntax doesn't have an IP (Swarm publishes the ports on all the nodes).
 		// Thus, IP is blank.
 		komposePorts = append(komposePorts, kobject.Ports{
 			HostPort:      int32(port.Published),
 			ContainerPort: int32(port.Target),
 			HostIP:        "",
-			Protocol:      api.Protocol(strings.ToUpper(string(port.Protocol))),
+			Protocol:      protocol,
+			Mode:          mode,
 		})
 
 	}
@@ -162,13 +535,252 @@ func loadV3Ports(ports []types.ServicePortConfig) []kobject.Ports {
 	return komposePorts
 }
 
-func dockerComposeToKomposeMapping(composeObject *types.Config) (kobject.KomposeObject, error) {
+// loadV3RawPorts loads the ports of a service, parsed by kompose as docker/cli drops the host IP
+// of the short syntax. The short syntax is parsed like in v1 / v2, the long syntax by loadV3Ports
+func loadV3RawPorts(rawPorts interface{}) ([]kobject.Ports, error) {
+	ports, ok := rawPorts.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid type %T for ports", rawPorts)
+	}
+
+	komposePorts := []kobject.Ports{}
+	for _, port := range ports {
+		portDict, ok := port.(map[string]interface{})
+		if !ok {
+			portSpecs, err := loadPortSpec(fmt.Sprint(port))
+			if err != nil {
+				return nil, err
+			}
+			komposePorts = append(komposePorts, portSpecs...)
+			continue
+		}
+
+		portConfig := types.ServicePortConfig{}
+		for key, value := range portDict {
+			var err error
+			switch key {
+			case "target":
+				portConfig.Target, err = loadV3PortNumber(value)
+			case "published":
+				portConfig.Published, err = loadV3PortNumber(value)
+			case "protocol":
+				portConfig.Protocol = fmt.Sprint(value)
+			case "mode":
+				portConfig.Mode = fmt.Sprint(value)
+			default:
+				err = fmt.Errorf("unsupported key %s", key)
+			}
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid port %v", portDict)
+			}
+		}
+		if portConfig.Target == 0 {
+			return nil, fmt.Errorf("invalid port %v, target is required", portDict)
+		}
+		if portConfig.Protocol != "" && !strings.EqualFold(portConfig.Protocol, "tcp") && !strings.EqualFold(portConfig.Protocol, "udp") {
+			return nil, fmt.Errorf("invalid protocol %q", portConfig.Protocol)
+		}
+		if portConfig.Mode != "" && portConfig.Mode != "ingress" && portConfig.Mode != kobject.PortModeHost {
+			return nil, fmt.Errorf("invalid mode %q of port %v, it must be ingress or host", portConfig.Mode, portDict)
+		}
+		komposePorts = append(komposePorts, loadV3Ports([]types.ServicePortConfig{portConfig})...)
+	}
+	return komposePorts, nil
+}
+
+// loadV3PortNumber loads the target or published port of the long syntax, an integer or an interpolated string
+func loadV3PortNumber(value interface{}) (uint32, error) {
+	port, err := strconv.ParseUint(fmt.Sprint(value), 10, 16)
+	if err != nil {
+		return 0, err
+	}
+	return uint32(port), nil
+}
+
+// Convert a Docker Compose healthcheck to kobject.HealthCheck
+// See: https://docs.docker.com/compose/compose-file/#healthcheck
+func parseHealthCheck(composeHealthCheck types.HealthCheckConfig) (kobject.HealthCheck, error) {
+	var timeout, interval, retries, startPeriod int32
+
+	// Here we convert the timeout from 1h30s (example) to 36030 seconds.
+	if composeHealthCheck.Timeout != "" {
+		parse, err := time.ParseDuration(composeHealthCheck.Timeout)
+		if err != nil {
+			return kobject.HealthCheck{}, errors.Wrap(err, "unable to parse health check timeout variable")
+		}
+		timeout = int32(parse.Seconds())
+	}
+
+	if composeHealthCheck.Interval != "" {
+		parse, err := time.ParseDuration(composeHealthCheck.Interval)
+		if err != nil {
+			return kobject.HealthCheck{}, errors.Wrap(err, "unable to parse health check interval variable")
+		}
+		interval = int32(parse.Seconds())
+	}
+
+	if composeHealthCheck.Retries != nil {
+		retries = int32(*composeHealthCheck.Retries)
+	}
+
+	if composeHealthCheck.StartPeriod != "" {
+		parse, err := time.ParseDuration(composeHealthCheck.StartPeriod)
+		if err != nil {
+			return kobject.HealthCheck{}, errors.Wrap(err, "unable to parse health check start_period variable")
+		}
+		startPeriod = int32(parse.Seconds())
+	}
+
+	// ["NONE"] is the same as "disable: true"
+	test := []string(composeHealthCheck.Test)
+	disable := composeHealthCheck.Disable
+	if len(test) > 0 && test[0] == "NONE" {
+		disable = true
+		test = nil
+	}
+
+	// Due to docker/cli adding "CMD-SHELL" to the struct, we convert it
+	// to the command kubernetes will run within the container
+	// See: https://docs.docker.com/engine/reference/builder/#healthcheck
+	if len(test) > 0 {
+		switch test[0] {
+		case "CMD":
+			test = test[1:]
+		case "CMD-SHELL":
+			test = append([]string{"/bin/sh", "-c"}, strings.Join(test[1:], " "))
+		}
+	}
+
+	// Due to docker/cli defaulting the value of each of these to 0 when not provided
+	// we only set what was actually passed in
+	return kobject.HealthCheck{
+		Test:        test,
+		Timeout:     timeout,
+		Interval:    interval,
+		Retries:     retries,
+		StartPeriod: startPeriod,
+		Disable:     disable,
+	}, nil
+}
+
+// loadUpdateConfig loads a raw deploy.update_config or deploy.rollback_config, named key
+// See: https://docs.docker.com/compose/compose-file/#update_config
+func loadUpdateConfig(key string, rawUpdateConfig interface{}) (*kobject.UpdateConfig, error) {
+	values, ok := rawUpdateConfig.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid type %T for %s", rawUpdateConfig, key)
+	}
+
+	// like Docker, one container is updated at once by default
+	updateConfig := kobject.UpdateConfig{Parallelism: 1, Order: "stop-first"}
+	for name, value := range values {
+		switch name {
+		case "parallelism":
+			parallelism, err := strconv.ParseUint(fmt.Sprint(value), 10, 64)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid %s.parallelism", key)
+			}
+			updateConfig.Parallelism = parallelism
+		case "delay", "monitor":
+			duration, err := time.ParseDuration(fmt.Sprint(value))
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid %s.%s", key, name)
+			}
+			if name == "delay" {
+				updateConfig.Delay = int32(duration.Seconds())
+			} else {
+				updateConfig.Monitor = int32(duration.Seconds())
+			}
+		case "failure_action":
+			updateConfig.FailureAction = fmt.Sprint(value)
+			if updateConfig.FailureAction != "continue" && updateConfig.FailureAction != "rollback" && updateConfig.FailureAction != "pause" {
+				return nil, fmt.Errorf("invalid %s.failure_action %q, must be continue, rollback or pause", key, updateConfig.FailureAction)
+			}
+		case "max_failure_ratio":
+			ratio, err := strconv.ParseFloat(fmt.Sprint(value), 64)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid %s.max_failure_ratio", key)
+			}
+			updateConfig.MaxFailureRatio = ratio
+		case "order":
+			updateConfig.Order = fmt.Sprint(value)
+			if updateConfig.Order != "stop-first" && updateConfig.Order != "start-first" {
+				return nil, fmt.Errorf("invalid %s.order %q, must be stop-first or start-first", key, updateConfig.Order)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported key %s in %s", name, key)
+		}
+	}
+	return &updateConfig, nil
+}
+
+// handleHealthCheckLabel handles the kompose.service.healthcheck.* labels
+// which turn the health check into an HTTP GET or TCP socket probe
+func handleHealthCheckLabel(healthCheck *kobject.HealthCheck, key string, value string) error {
+	switch key {
+	case "kompose.service.healthcheck.http_get_path":
+		healthCheck.HTTPGetPath = value
+	case "kompose.service.healthcheck.http_get_port", "kompose.service.healthcheck.tcp_port":
+		port, err := strconv.Atoi(value)
+		if err != nil {
+			return errors.Wrapf(err, "invalid port %q in %s label", value, key)
+		}
+		if key == "kompose.service.healthcheck.tcp_port" {
+			healthCheck.TCPPort = int32(port)
+		} else {
+			healthCheck.HTTPGetPort = int32(port)
+		}
+	}
+	return nil
+}
+
+// extraKeys holds the keys of each service that docker/cli didn't parse, topLevelNames the names
+// of the top level entries, which name the objects they become, and composeFileDir is the directory that relative paths of the compose file are resolved against
+func dockerComposeToKomposeMapping(composeObject *types.Config, extraKeys map[string]interface{}, topLevelNames map[string]map[string]string, composeFileDir string) (kobject.KomposeObject, error) {
 
 	// Step 1. Initialize what's going to be returned
 	komposeObject := kobject.KomposeObject{
 		ServiceConfigs: make(map[string]kobject.ServiceConfig),
+		NetworkConfigs: map[string]kobject.NetworkConfig{defaultNetwork: {}},
 		LoadedFrom:     "compose",
 	}
+	// the networks are named after their name key, which the services are attached by
+	networkName := func(name string) string {
+		if entryName, ok := topLevelNames["networks"][name]; ok {
+			return entryName
+		}
+		return name
+	}
+	for name, networkConfig := range composeObject.Networks {
+		komposeObject.NetworkConfigs[normalizeServiceNames(networkName(name))] = kobject.NetworkConfig{Internal: networkConfig.Internal}
+	}
+	if len(composeObject.Secrets) > 0 {
+		komposeObject.Secrets = make(map[string]kobject.FileObject)
+	}
+	// docker/cli resolves the files of secrets and configs against the working directory, but doesn't keep them
+	for name, secretConfig := range composeObject.Secrets {
+		secret, err := loadFileObject("secret", name, secretConfig.File, secretConfig.External.External, secretConfig.External.Name, composeFileDir)
+		if err != nil {
+			return kobject.KomposeObject{}, err
+		}
+		if entryName, ok := topLevelNames["secrets"][name]; ok {
+			secret.Name = normalizeServiceNames(entryName)
+		}
+		komposeObject.Secrets[name] = secret
+	}
+	if len(composeObject.Configs) > 0 {
+		komposeObject.Configs = make(map[string]kobject.FileObject)
+	}
+	for name, configObjConfig := range composeObject.Configs {
+		config, err := loadFileObject("config", name, configObjConfig.File, configObjConfig.External.External, configObjConfig.External.Name, composeFileDir)
+		if err != nil {
+			return kobject.KomposeObject{}, err
+		}
+		if entryName, ok := topLevelNames["configs"][name]; ok {
+			config.Name = normalizeServiceNames(entryName)
+		}
+		komposeObject.Configs[name] = config
+	}
 
 	// Step 2. Parse through the object and conver it to kobject.KomposeObject!
 	// Here we "clean up" the service configuration so we return something that includes
@@ -184,8 +796,12 @@ func dockerComposeToKomposeMapping(composeObject *types.Config) (kobject.Kompose
 		serviceConfig.Annotations = map[string]string(composeServiceConfig.Labels)
 		serviceConfig.CapAdd = composeServiceConfig.CapAdd
 		serviceConfig.CapDrop = composeServiceConfig.CapDrop
+		serviceConfig.SecurityOpt = composeServiceConfig.SecurityOpt
+		serviceConfig.ReadOnly = composeServiceConfig.ReadOnly
 		serviceConfig.Expose = composeServiceConfig.Expose
+		serviceConfig.Pid = composeServiceConfig.Pid
 		serviceConfig.Privileged = composeServiceConfig.Privileged
+		serviceConfig.Restart = composeServiceConfig.Restart
 		serviceConfig.User = composeServiceConfig.User
 		serviceConfig.Stdin = composeServiceConfig.StdinOpen
 		serviceConfig.Tty = composeServiceConfig.Tty
@@ -193,6 +809,10 @@ func dockerComposeToKomposeMapping(composeObject *types.Config) (kobject.Kompose
 		serviceConfig.ContainerName = composeServiceConfig.ContainerName
 		serviceConfig.Command = composeServiceConfig.Entrypoint
 		serviceConfig.Args = composeServiceConfig.Command
+		if composeServiceConfig.StopGracePeriod != nil {
+			serviceConfig.StopGracePeriod = composeServiceConfig.StopGracePeriod.String()
+		}
+		serviceConfig.StopSignal = loadStopSignal(name, composeServiceConfig.StopSignal)
 
 		//
 		// Deploy keys
@@ -225,7 +845,7 @@ func dockerComposeToKomposeMapping(composeObject *types.Config) (kobject.Kompose
 
 		}
 
-		// restart-policy:
+		// restart-policy, it takes precedence over the restart key:
 		if composeServiceConfig.Deploy.RestartPolicy != nil {
 			serviceConfig.Restart = composeServiceConfig.Deploy.RestartPolicy.Condition
 		}
@@ -235,11 +855,129 @@ func dockerComposeToKomposeMapping(composeObject *types.Config) (kobject.Kompose
 			serviceConfig.Replicas = int(*composeServiceConfig.Deploy.Replicas)
 		}
 
-		// TODO: Build is not yet supported, see:
+		// Keys that docker/cli didn't parse
+		serviceExtraKeys, _ := extraKeys[name].(map[string]interface{})
+
+		// healthcheck:
+		if rawHealthCheck, ok := serviceExtraKeys["healthcheck"]; ok {
+			composeHealthCheck, err := loadHealthCheck(rawHealthCheck)
+			if err != nil {
+				return kobject.KomposeObject{}, errors.Wrap(err, "loadHealthCheck failed. "+name+" failed to load healthcheck from compose file")
+			}
+			healthCheck, err := parseHealthCheck(composeHealthCheck)
+			if err != nil {
+				return kobject.KomposeObject{}, errors.Wrap(err, "Unable to parse health check")
+			}
+			serviceConfig.HealthChecks = healthCheck
+		}
+
+		// build:
+		// docker/cli doesn't keep build, see:
 		// https://github.com/docker/cli/blob/master/cli/compose/types/types.go#L9
-		// We will have to *manually* add this / parse.
-		// serviceConfig.Build = composeServiceConfig.Build.Context
-		// serviceConfig.Dockerfile = composeServiceConfig.Build.Dockerfile
+		// so it is parsed from the compose file
+		if rawBuild, ok := serviceExtraKeys["build"]; ok {
+			if err := loadV3Build(rawBuild, composeFileDir, &serviceConfig); err != nil {
+				return kobject.KomposeObject{}, errors.Wrap(err, "loadV3Build failed. "+name+" failed to load build from compose file")
+			}
+			// Validate dockerfile path
+			if filepath.IsAbs(serviceConfig.Dockerfile) {
+				return kobject.KomposeObject{}, fmt.Errorf("%q defined in service %q is an absolute path, it must be a relative path", serviceConfig.Dockerfile, name)
+			}
+		}
+
+		// hostname and domainname:
+		serviceConfig.Hostname = loadDNSLabel("hostname", name, composeServiceConfig.Hostname)
+		serviceConfig.DomainName = loadDNSLabel("domainname", name, composeServiceConfig.DomainName)
+
+		// mode:
+		serviceConfig.DeployMode = composeServiceConfig.Deploy.Mode
+
+		// update_config and rollback_config:
+		for _, key := range []string{"deploy.update_config", "deploy.rollback_config"} {
+			rawUpdateConfig, ok := serviceExtraKeys[key]
+			if !ok {
+				continue
+			}
+			updateConfig, err := loadUpdateConfig(key, rawUpdateConfig)
+			if err != nil {
+				return kobject.KomposeObject{}, errors.Wrap(err, "loadUpdateConfig failed. "+name+" failed to load "+key+" from compose file")
+			}
+			if key == "deploy.update_config" {
+				serviceConfig.UpdateConfig = updateConfig
+			} else {
+				serviceConfig.RollbackConfig = updateConfig
+			}
+		}
+
+		// placement:
+		var spreads []string
+		for _, preference := range composeServiceConfig.Deploy.Placement.Preferences {
+			spreads = append(spreads, preference.Spread)
+		}
+		serviceConfig.Placement = loadPlacement(name, composeServiceConfig.Deploy.Placement.Constraints, spreads)
+
+		// tmpfs volumes and shm_size:
+		if tmpfsVolumes, ok := serviceExtraKeys["tmpfs_volumes"].([]interface{}); ok {
+			for _, tmpfs := range tmpfsVolumes {
+				serviceConfig.TmpFs = append(serviceConfig.TmpFs, fmt.Sprint(tmpfs))
+			}
+		}
+		if rawShmSize, ok := serviceExtraKeys["shm_size"]; ok {
+			shmSize, err := units.RAMInBytes(fmt.Sprint(rawShmSize))
+			if err != nil {
+				return kobject.KomposeObject{}, errors.Wrap(err, "invalid shm_size of service "+name)
+			}
+			serviceConfig.ShmSize = libcomposeyaml.MemStringorInt(shmSize)
+		}
+
+		// group_add:
+		if rawGroupAdd, ok := serviceExtraKeys["group_add"]; ok {
+			for _, group := range concatLists(rawGroupAdd) {
+				serviceConfig.GroupAdd = append(serviceConfig.GroupAdd, fmt.Sprint(group))
+			}
+		}
+
+		// sysctls and ulimits:
+		if rawSysctls, ok := serviceExtraKeys["sysctls"]; ok {
+			sysctls, err := loadSysctls(rawSysctls)
+			if err != nil {
+				return kobject.KomposeObject{}, errors.Wrap(err, "loadSysctls failed. "+name+" failed to load sysctls from compose file")
+			}
+			serviceConfig.Sysctls = sysctls
+		}
+		var ulimitNames []string
+		for ulimitName := range composeServiceConfig.Ulimits {
+			ulimitNames = append(ulimitNames, ulimitName)
+		}
+		sort.Strings(ulimitNames)
+		for _, ulimitName := range ulimitNames {
+			ulimit := composeServiceConfig.Ulimits[ulimitName]
+			soft, hard := ulimit.Soft, ulimit.Hard
+			if ulimit.Single != 0 {
+				soft, hard = ulimit.Single, ulimit.Single
+			}
+			serviceConfig.Ulimits = append(serviceConfig.Ulimits, kobject.Ulimit{Name: ulimitName, Soft: int64(soft), Hard: int64(hard)})
+		}
+
+		// depends_on:
+		if rawDependsOn, ok := serviceExtraKeys["depends_on"]; ok {
+			dependsOn, err := loadDependsOn(rawDependsOn)
+			if err != nil {
+				return kobject.KomposeObject{}, errors.Wrap(err, "loadDependsOn failed. "+name+" failed to load depends_on from compose file")
+			}
+			serviceConfig.DependsOn = dependsOn
+		}
+
+		// load links and external_links, whose aliases become Services
+		var err error
+		serviceConfig.Links, err = loadLinks(name, "links", composeServiceConfig.Links, composeServiceConfig.Labels)
+		if err != nil {
+			return kobject.KomposeObject{}, errors.Wrap(err, "loadLinks failed. "+name+" failed to load links from compose file")
+		}
+		serviceConfig.ExternalLinks, err = loadLinks(name, "external_links", composeServiceConfig.ExternalLinks, composeServiceConfig.Labels)
+		if err != nil {
+			return kobject.KomposeObject{}, errors.Wrap(err, "loadLinks failed. "+name+" failed to load external_links from compose file")
+		}
 
 		// Gather the environment values
 		// DockerCompose uses map[string]*string while we use []string
@@ -249,16 +987,63 @@ func dockerComposeToKomposeMapping(composeObject *types.Config) (kobject.Kompose
 			serviceConfig.Environment = append(serviceConfig.Environment, env)
 		}
 
+		// env_file:
+		if rawEnvFile, ok := serviceExtraKeys["env_file"]; ok {
+			envFilePaths, err := loadEnvFilePaths(rawEnvFile)
+			if err != nil {
+				return kobject.KomposeObject{}, errors.Wrap(err, "loadEnvFilePaths failed. "+name+" failed to load env_file from compose file")
+			}
+			envFiles, err := loadEnvFiles(normalizeServiceNames(name), envFilePaths, composeFileDir)
+			if err != nil {
+				return kobject.KomposeObject{}, errors.Wrap(err, "loadEnvFiles failed")
+			}
+			serviceConfig.EnvFile = envFiles
+		}
+
 		// Parse the ports
 		// v3 uses a new format called "long syntax" starting in 3.2
 		// https://docs.docker.com/compose/compose-file/#ports
-		serviceConfig.Port = loadV3Ports(composeServiceConfig.Ports)
+		serviceConfig.Port = []kobject.Ports{}
+		if rawPorts, ok := serviceExtraKeys["ports"]; ok {
+			serviceConfig.Port, err = loadV3RawPorts(rawPorts)
+			if err != nil {
+				return kobject.KomposeObject{}, errors.Wrap(err, "loadV3RawPorts failed. "+name+" failed to load ports from compose file")
+			}
+		}
 
 		// Parse the volumes
 		// Again, in v3, we use the "long syntax" for volumes in terms of parsing
 		// https://docs.docker.com/compose/compose-file/#long-syntax-2
 		serviceConfig.VolList = loadV3Volumes(composeServiceConfig.Volumes)
 
+		var networks []string
+		for network := range composeServiceConfig.Networks {
+			networks = append(networks, networkName(network))
+		}
+		serviceConfig.Network = loadServiceNetworks(networks, composeServiceConfig.NetworkMode)
+		// the kompose.pod.group label takes precedence over network_mode
+		if podGroup := loadNetworkMode(name, composeServiceConfig.NetworkMode); serviceConfig.PodGroup == "" {
+			serviceConfig.PodGroup = podGroup
+		}
+
+		// secrets:
+		for _, secret := range composeServiceConfig.Secrets {
+			reference, err := loadFileReference("secret", secret.Source, secret.Target, secret.UID, secret.GID, secret.Mode, komposeObject.Secrets)
+			if err != nil {
+				return kobject.KomposeObject{}, errors.Wrapf(err, "service %q", name)
+			}
+			serviceConfig.Secrets = append(serviceConfig.Secrets, reference)
+		}
+
+		// configs:
+		for _, config := range composeServiceConfig.Configs {
+			reference, err := loadFileReference("config", config.Source, config.Target, config.UID, config.GID, config.Mode, komposeObject.Configs)
+			if err != nil {
+				return kobject.KomposeObject{}, errors.Wrapf(err, "service %q", name)
+			}
+			serviceConfig.Configs = append(serviceConfig.Configs, reference)
+		}
+
 		// Label handler
 		// Labels used to influence conversion of kompose will be handled
 		// from here for docker-compose. Each loader will have such handler.
@@ -273,6 +1058,24 @@ func dockerComposeToKomposeMapping(composeObject *types.Config) (kobject.Kompose
 				serviceConfig.ServiceType = serviceType
 			case "kompose.service.expose":
 				serviceConfig.ExposeService = strings.ToLower(value)
+			case "kompose.service.healthcheck.http_get_path", "kompose.service.healthcheck.http_get_port", "kompose.service.healthcheck.tcp_port":
+				if err := handleHealthCheckLabel(&serviceConfig.HealthChecks, key, value); err != nil {
+					return kobject.KomposeObject{}, errors.Wrap(err, "handleHealthCheckLabel failed")
+				}
+			case "kompose.service.wait_for_dependencies":
+				waitForDependencies, err := handleBoolLabel(key, value)
+				if err != nil {
+					return kobject.KomposeObject{}, err
+				}
+				serviceConfig.WaitForDependencies = waitForDependencies
+			case "kompose.service.wrap_ulimit_nofile":
+				wrapUlimitNofile, err := handleBoolLabel(key, value)
+				if err != nil {
+					return kobject.KomposeObject{}, err
+				}
+				serviceConfig.WrapUlimitNofile = wrapUlimitNofile
+			case "kompose.pod.group":
+				serviceConfig.PodGroup = normalizeServiceNames(value)
 			}
 		}
 
@@ -284,7 +1087,35 @@ func dockerComposeToKomposeMapping(composeObject *types.Config) (kobject.Kompose
 		// Final step, add to the array!
 		komposeObject.ServiceConfigs[normalizeServiceNames(name)] = serviceConfig
 	}
-	handleVolume(&komposeObject)
+
+	// Step 3. Load the top level volumes, which are shared by the services mounting them
+	namedVolumes := make(map[string]namedVolume)
+	for name, volumeConfig := range composeObject.Volumes {
+		volume, err := loadNamedVolume(name, volumeConfig.DriverOpts, volumeConfig.Labels, volumeConfig.External.External, volumeConfig.External.Name)
+		if err != nil {
+			return kobject.KomposeObject{}, err
+		}
+		if entryName, ok := topLevelNames["volumes"][name]; ok {
+			volume.claimName = normalizeServiceNames(entryName)
+		}
+		namedVolumes[normalizeServiceNames(name)] = volume
+	}
+	handleVolume(&komposeObject, namedVolumes)
+
+	// the volumes of v3 all come from the volumes key, as there is no volumes_from, so they can be found by mount path
+	for name, rawServiceExtraKeys := range extraKeys {
+		serviceExtraKeys, _ := rawServiceExtraKeys.(map[string]interface{})
+		subPaths, _ := serviceExtraKeys["volume_subpaths"].(map[string]interface{})
+		if len(subPaths) == 0 {
+			continue
+		}
+		service := komposeObject.ServiceConfigs[normalizeServiceNames(name)]
+		for i, volume := range service.Volumes {
+			if subPath, ok := subPaths[volume.Container]; ok {
+				service.Volumes[i].SubPath = fmt.Sprint(subPath)
+			}
+		}
+	}
 
 	return komposeObject, nil
 }
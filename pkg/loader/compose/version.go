@@ -0,0 +1,284 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package compose
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// composeVersion is the parsed version of a compose file
+type composeVersion struct {
+	major int
+	minor int
+}
+
+func (v composeVersion) String() string {
+	return fmt.Sprintf("%d.%d", v.major, v.minor)
+}
+
+// latest minor version supported for each major version
+var latestMinorVersions = map[int]int{
+	1: 0,
+	2: 4,
+	3: 9,
+}
+
+// latest minor version the vendored parsers can validate for each major version,
+// libcompose only knows 2.0 and docker/cli knows up to 3.3
+var parserMinorVersions = map[int]int{
+	1: 0,
+	2: 0,
+	3: 3,
+}
+
+// serviceKeyVersions lists the service keys which were added in a minor version,
+// by major version. Nested keys are separated by ".".
+// See: https://docs.docker.com/compose/compose-file/compose-versioning/
+var serviceKeyVersions = map[int]map[string]int{
+	2: {
+		"build.isolation":          1,
+		"healthcheck":              1,
+		"isolation":                1,
+		"pids_limit":               1,
+		"storage_opt":              1,
+		"sysctls":                  1,
+		"userns_mode":              1,
+		"blkio_config":             2,
+		"build.cache_from":         2,
+		"build.labels":             2,
+		"build.network":            2,
+		"cpu_count":                2,
+		"cpu_percent":              2,
+		"cpus":                     2,
+		"init":                     2,
+		"scale":                    2,
+		"build.extra_hosts":        3,
+		"build.shm_size":           3,
+		"build.target":             3,
+		"device_cgroup_rules":      3,
+		"healthcheck.start_period": 3,
+		"runtime":                  3,
+		"platform":                 4,
+	},
+	3: {
+		"secrets":                                1,
+		"build.cache_from":                       2,
+		"deploy.endpoint_mode":                   2,
+		"build.labels":                           3,
+		"configs":                                3,
+		"credential_spec":                        3,
+		"deploy.placement.preferences":           3,
+		"build.network":                          4,
+		"build.target":                           4,
+		"deploy.update_config.order":             4,
+		"healthcheck.start_period":               4,
+		"build.shm_size":                         5,
+		"isolation":                              5,
+		"deploy.rollback_config":                 7,
+		"init":                                   7,
+		"deploy.placement.max_replicas_per_node": 8,
+	},
+}
+
+// topLevelKeyVersions lists the top level keys which were added in a minor version, by major version
+var topLevelKeyVersions = map[int]map[string]int{
+	3: {
+		"secrets": 1,
+		"configs": 3,
+	},
+}
+
+// topLevelEntryKeyVersions lists the keys of the entries of the top level sections which were added
+// in a minor version, by major version. The section and the key are separated by ".".
+var topLevelEntryKeyVersions = map[int]map[string]int{
+	3: {
+		"volumes.name":  4,
+		"configs.name":  5,
+		"networks.name": 5,
+		"secrets.name":  5,
+	},
+}
+
+// extension fields ("x-" keys) are allowed at the top level from these minor versions
+var extensionFieldVersions = map[int]int{
+	2: 1,
+	3: 4,
+}
+
+// parseComposeVersion parses the version of a compose file,
+// a file without version is a version 1 file
+func parseComposeVersion(version string) (composeVersion, error) {
+	if version == "" {
+		return composeVersion{major: 1}, nil
+	}
+
+	parts := strings.Split(version, ".")
+	if len(parts) > 2 {
+		return composeVersion{}, fmt.Errorf("invalid version %q", version)
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return composeVersion{}, fmt.Errorf("invalid version %q", version)
+	}
+	minor := 0
+	if len(parts) == 2 {
+		minor, err = strconv.Atoi(parts[1])
+		if err != nil {
+			return composeVersion{}, fmt.Errorf("invalid version %q", version)
+		}
+	}
+
+	latestMinor, ok := latestMinorVersions[major]
+	if !ok || minor > latestMinor {
+		return composeVersion{}, fmt.Errorf("Version %s of Docker Compose is not supported. Please use version 1, 2 (up to 2.4) or 3 (up to 3.9)", version)
+	}
+	return composeVersion{major: major, minor: minor}, nil
+}
+
+// lookupKey returns the value of a nested key, maps can come either from docker/cli or from libcompose
+func lookupKey(dict interface{}, path []string) (interface{}, bool) {
+	var value interface{}
+	var ok bool
+	switch d := dict.(type) {
+	case map[string]interface{}:
+		value, ok = d[path[0]]
+	case map[interface{}]interface{}:
+		value, ok = d[path[0]]
+	}
+	if !ok || len(path) == 1 {
+		return value, ok
+	}
+	return lookupKey(value, path[1:])
+}
+
+// deleteKey removes a nested key
+func deleteKey(dict interface{}, path []string) {
+	if len(path) > 1 {
+		if value, ok := lookupKey(dict, path[:1]); ok {
+			deleteKey(value, path[1:])
+		}
+		return
+	}
+	switch d := dict.(type) {
+	case map[string]interface{}:
+		delete(d, path[0])
+	case map[interface{}]interface{}:
+		delete(d, path[0])
+	}
+}
+
+// sortedKeyPaths returns the keys of a key version table, sorted for stable errors and warnings
+func sortedKeyPaths(keyVersions map[string]int) []string {
+	var keys []string
+	for key := range keyVersions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortedEntryNames returns the names of the entries of a top level section, sorted for stable errors
+func sortedEntryNames(entries map[string]interface{}) []string {
+	var names []string
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checkServiceKeyVersions returns an error if a service uses a key which was added after the declared version
+func checkServiceKeyVersions(name string, service interface{}, version composeVersion) error {
+	keyVersions := serviceKeyVersions[version.major]
+	for _, key := range sortedKeyPaths(keyVersions) {
+		minor := keyVersions[key]
+		if _, ok := lookupKey(service, strings.Split(key, ".")); ok && minor > version.minor {
+			return fmt.Errorf("%s key of service %q requires version %d.%d or later of Docker Compose, but the file is version %s", key, name, version.major, minor, version)
+		}
+	}
+	return nil
+}
+
+// checkTopLevelKeyVersions returns an error if the compose file uses a top level key which was added after the declared version
+func checkTopLevelKeyVersions(config map[string]interface{}, version composeVersion) error {
+	// in version 1, the top level keys are the services
+	if version.major == 1 {
+		return nil
+	}
+	keyVersions := topLevelKeyVersions[version.major]
+	for _, key := range sortedKeyPaths(keyVersions) {
+		if _, ok := config[key]; ok && keyVersions[key] > version.minor {
+			return fmt.Errorf("%s key requires version %d.%d or later of Docker Compose, but the file is version %s", key, version.major, keyVersions[key], version)
+		}
+	}
+	entryKeyVersions := topLevelEntryKeyVersions[version.major]
+	for _, key := range sortedKeyPaths(entryKeyVersions) {
+		minor := entryKeyVersions[key]
+		if minor <= version.minor {
+			continue
+		}
+		path := strings.SplitN(key, ".", 2)
+		entries, _ := config[path[0]].(map[string]interface{})
+		for _, name := range sortedEntryNames(entries) {
+			if _, ok := lookupKey(entries[name], path[1:]); ok {
+				return fmt.Errorf("%s key of %s %q requires version %d.%d or later of Docker Compose, but the file is version %s", path[1], strings.TrimSuffix(path[0], "s"), name, version.major, minor, version)
+			}
+		}
+	}
+	for key := range config {
+		if strings.HasPrefix(key, "x-") {
+			minor, ok := extensionFieldVersions[version.major]
+			if !ok || minor > version.minor {
+				return fmt.Errorf("extension field %s requires version %d.%d or later of Docker Compose, but the file is version %s", key, version.major, minor, version)
+			}
+		}
+	}
+	return nil
+}
+
+// removeUnparsableServiceKeys removes the keys of a service which were added after
+// the version the vendored parser knows about, and warns once for each key in warned
+func removeUnparsableServiceKeys(service interface{}, version composeVersion, warned map[string]bool) {
+	keyVersions := serviceKeyVersions[version.major]
+	for _, key := range sortedKeyPaths(keyVersions) {
+		if keyVersions[key] <= parserMinorVersions[version.major] {
+			continue
+		}
+		path := strings.Split(key, ".")
+		if _, ok := lookupKey(service, path); ok {
+			if !warned[key] {
+				log.Warningf("Unsupported %s key - ignoring", key)
+				warned[key] = true
+			}
+			deleteKey(service, path)
+		}
+	}
+}
+
+// removeExtensionFields removes the top level "x-" keys, which are meant to be ignored
+func removeExtensionFields(config map[string]interface{}) {
+	for key := range config {
+		if strings.HasPrefix(key, "x-") {
+			delete(config, key)
+		}
+	}
+}
@@ -38,6 +38,7 @@ import (
 	"k8s.io/kubernetes/pkg/api/unversioned"
 	"k8s.io/kubernetes/pkg/apis/extensions"
 	"k8s.io/kubernetes/pkg/runtime"
+	"k8s.io/kubernetes/pkg/security/apparmor"
 
 	"sort"
 
@@ -279,7 +280,7 @@ func convertToVersion(obj runtime.Object, groupVersion unversioned.GroupVersion)
 
 // PortsExist checks if service has ports defined
 func (k *Kubernetes) PortsExist(name string, service kobject.ServiceConfig) bool {
-	if len(service.Port) == 0 {
+	if len(PodPorts(service)) == 0 {
 		log.Debugf("[%s] No ports defined. Headless service will be created.", name)
 		return false
 	}
@@ -340,7 +341,7 @@ func (k *Kubernetes) UpdateKubernetesObjects(name string, service kobject.Servic
 		return errors.Wrap(err, "k.ConfigVolumes failed")
 	}
 	// Configure Tmpfs
-	if len(service.TmpFs) > 0 {
+	if len(service.TmpFs) > 0 || service.ShmSize != 0 {
 		TmpVolumesMount, TmpVolumes := k.ConfigTmpfs(name, service)
 
 		volumes = append(volumes, TmpVolumes...)
@@ -349,6 +350,14 @@ func (k *Kubernetes) UpdateKubernetesObjects(name string, service kobject.Servic
 
 	}
 
+	// Configure the secrets and configs
+	if len(service.Secrets) > 0 || len(service.Configs) > 0 {
+		fileVolumesMount, fileVolumes := k.ConfigFileVolumes(name, service)
+		volumes = append(volumes, fileVolumes...)
+		volumesMount = append(volumesMount, fileVolumesMount...)
+	}
+	fsGroup := k.ConfigFilesGroup(name, service)
+
 	if pvc != nil {
 		// Looping on the slice pvc instead of `*objects = append(*objects, pvc...)`
 		// because the type of objects and pvc is different, but when doing append
@@ -358,35 +367,67 @@ func (k *Kubernetes) UpdateKubernetesObjects(name string, service kobject.Servic
 		}
 	}
 
+	// Configure the ConfigMaps of env_file
+	for _, envFile := range service.EnvFile {
+		*objects = append(*objects, k.InitConfigMap(envFile))
+	}
+
 	// Configure the container ports.
 	ports := k.ConfigPorts(name, service)
 
 	// Configure capabilities
 	capabilities := k.ConfigCapabilities(service)
 
+	// Configure the liveness and readiness probes
+	livenessProbe := k.ConfigProbe(service.HealthChecks)
+	readinessProbe := k.ConfigProbe(service.HealthChecks)
+
 	// Configure annotations
 	annotations := transformer.ConfigAnnotations(service)
 
+	// Configure the sysctls, the placement and the AppArmor and seccomp profiles, which are set by pod annotations
+	podAnnotations := k.ConfigSysctls(name, service)
+	seLinuxOptions, securityOptAnnotations := k.ConfigSecurityOpts(name, service)
+	for key, value := range securityOptAnnotations {
+		podAnnotations[key] = value
+	}
+	nodeSelector, affinityAnnotations, err := k.ConfigPlacement(name, service)
+	if err != nil {
+		return errors.Wrap(err, "k.ConfigPlacement failed")
+	}
+	for key, value := range affinityAnnotations {
+		podAnnotations[key] = value
+	}
+
+	// Configure the command, wrapped to set the nofile ulimit
+	command, args := k.ConfigCommand(name, service)
+
+	// Configure the grace period, and the preStop hook sending the stop_signal
+	terminationGracePeriod, err := DurationStrToSecondsInt(service.StopGracePeriod)
+	if err != nil {
+		log.Warningf("Failed to parse duration \"%v\" for service \"%v\"", service.StopGracePeriod, name)
+	}
+	lifecycle := k.ConfigStopSignal(service, terminationGracePeriod)
+
 	// fillTemplate fills the pod template with the value calculated from config
 	fillTemplate := func(template *api.PodTemplateSpec) error {
 		if len(service.ContainerName) > 0 {
 			template.Spec.Containers[0].Name = service.ContainerName
 		}
 		template.Spec.Containers[0].Env = envs
-		template.Spec.Containers[0].Command = service.Command
-		template.Spec.Containers[0].Args = service.Args
+		template.Spec.Containers[0].Command = command
+		template.Spec.Containers[0].Args = args
 		template.Spec.Containers[0].WorkingDir = service.WorkingDir
 		template.Spec.Containers[0].VolumeMounts = volumesMount
 		template.Spec.Containers[0].Stdin = service.Stdin
 		template.Spec.Containers[0].TTY = service.Tty
+		template.Spec.Containers[0].LivenessProbe = livenessProbe
+		template.Spec.Containers[0].ReadinessProbe = readinessProbe
+		template.Spec.Containers[0].Lifecycle = lifecycle
 		template.Spec.Volumes = volumes
-
-		if service.StopGracePeriod != "" {
-			template.Spec.TerminationGracePeriodSeconds, err = DurationStrToSecondsInt(service.StopGracePeriod)
-			if err != nil {
-				log.Warningf("Failed to parse duration \"%v\" for service \"%v\"", service.StopGracePeriod, name)
-			}
-		}
+		template.Spec.Hostname = service.Hostname
+		template.Spec.Subdomain = service.DomainName
+		template.Spec.TerminationGracePeriodSeconds = terminationGracePeriod
 
 		// Configure the resource limits
 		if service.MemLimit != 0 || service.CPULimit != 0 {
@@ -431,11 +472,28 @@ func (k *Kubernetes) UpdateKubernetesObjects(name string, service kobject.Servic
 			}
 		}
 
+		// the secret and config files are owned by the group of the volumes
+		podSecurityContext.FSGroup = fsGroup
+
+		// the groups of group_add have to be GIDs
+		for _, group := range service.GroupAdd {
+			gid, err := strconv.ParseInt(group, 10, 64)
+			if err != nil {
+				log.Warningf("Ignoring group %q of group_add for service %q. Group to be specified as a GID (numeric).", group, name)
+				continue
+			}
+			podSecurityContext.SupplementalGroups = append(podSecurityContext.SupplementalGroups, gid)
+		}
+
 		// Setup security context
 		securityContext := &api.SecurityContext{}
 		if service.Privileged {
 			securityContext.Privileged = &service.Privileged
 		}
+		if service.ReadOnly {
+			securityContext.ReadOnlyRootFilesystem = &service.ReadOnly
+		}
+		securityContext.SELinuxOptions = seLinuxOptions
 		if service.User != "" {
 			uid, err := strconv.ParseInt(service.User, 10, 64)
 			if err != nil {
@@ -460,6 +518,26 @@ func (k *Kubernetes) UpdateKubernetesObjects(name string, service kobject.Servic
 		}
 		template.Spec.Containers[0].Ports = ports
 		template.ObjectMeta.Labels = transformer.ConfigLabels(name)
+		if k.Opt.NetworkPolicies {
+			for key, value := range ConfigNetworkLabels(service) {
+				template.ObjectMeta.Labels[key] = value
+			}
+		}
+		if len(nodeSelector) > 0 {
+			template.Spec.NodeSelector = nodeSelector
+		}
+		if len(podAnnotations) > 0 {
+			if template.ObjectMeta.Annotations == nil {
+				template.ObjectMeta.Annotations = map[string]string{}
+			}
+			for key, value := range podAnnotations {
+				template.ObjectMeta.Annotations[key] = value
+			}
+		}
+		// the pods are selected by the headless Service of their subdomain
+		if service.DomainName != "" {
+			template.ObjectMeta.Labels[SubdomainLabel] = service.DomainName
+		}
 
 		// Configure the container restart policy.
 		switch service.Restart {
@@ -475,24 +553,119 @@ func (k *Kubernetes) UpdateKubernetesObjects(name string, service kobject.Servic
 		return nil
 	}
 
+	// Configure the rolling update from update_config
+	rollingUpdate, minReadySeconds := k.ConfigRollingUpdate(name, service)
+
 	// fillObjectMeta fills the metadata with the value calculated from config
 	fillObjectMeta := func(meta *api.ObjectMeta) {
 		meta.Annotations = annotations
 	}
 
+	// the pods are recreated when one of their containers has volumes
+	hasVolumes := len(service.Volumes) > 0
+	for _, container := range service.Containers {
+		hasVolumes = hasVolumes || len(container.Volumes) > 0
+	}
+
 	// update supported controller
 	for _, obj := range *objects {
 		err = k.UpdateController(obj, fillTemplate, fillObjectMeta)
 		if err != nil {
 			return errors.Wrap(err, "k.UpdateController failed")
 		}
-		if len(service.Volumes) > 0 {
+		if hasVolumes {
 			switch objType := obj.(type) {
 			case *extensions.Deployment:
 				objType.Spec.Strategy.Type = extensions.RecreateDeploymentStrategyType
 			case *deployapi.DeploymentConfig:
 				objType.Spec.Strategy.Type = deployapi.DeploymentStrategyTypeRecreate
 			}
+		} else if rollingUpdate != nil {
+			switch objType := obj.(type) {
+			case *extensions.Deployment:
+				objType.Spec.Strategy.Type = extensions.RollingUpdateDeploymentStrategyType
+				objType.Spec.Strategy.RollingUpdate = rollingUpdate
+				objType.Spec.MinReadySeconds = minReadySeconds
+			case *deployapi.DeploymentConfig:
+				objType.Spec.Strategy.Type = deployapi.DeploymentStrategyTypeRolling
+				objType.Spec.Strategy.RollingParams = &deployapi.RollingDeploymentStrategyParams{
+					MaxUnavailable: rollingUpdate.MaxUnavailable,
+					MaxSurge:       rollingUpdate.MaxSurge,
+				}
+				objType.Spec.MinReadySeconds = minReadySeconds
+			}
+		}
+	}
+
+	// Configure the containers of the services joining the pod
+	if err := k.UpdatePodContainers(name, service, objects); err != nil {
+		return errors.Wrap(err, "k.UpdatePodContainers failed")
+	}
+	return nil
+}
+
+// UpdatePodContainers adds the containers of the services joining the pod of a service to its pod template.
+// Each container is configured like the container of a service of its own, with its env, volumes and resources,
+// but the keys of these services setting the whole pod, like hostname, placement or sysctls, are ignored.
+func (k *Kubernetes) UpdatePodContainers(name string, service kobject.ServiceConfig, objects *[]runtime.Object) error {
+	for _, container := range service.Containers {
+		containerName := container.ContainerName
+		if container.Image == "" {
+			container.Image = containerName
+		}
+
+		containerObjects := []runtime.Object{k.InitD(containerName, container, 1)}
+		if err := k.UpdateKubernetesObjects(containerName, container, &containerObjects); err != nil {
+			return errors.Wrapf(err, "k.UpdateKubernetesObjects failed for container %s", containerName)
+		}
+		containerTemplate := containerObjects[0].(*extensions.Deployment).Spec.Template
+		// the PersistentVolumeClaims and the ConfigMaps of the env files of the container
+		*objects = append(*objects, containerObjects[1:]...)
+
+		// only the annotations of the AppArmor and seccomp profiles of the container are about the container,
+		// the others, like the sysctls or the affinity, set the whole pod
+		containerAnnotations := map[string]string{}
+		var podAnnotationKeys []string
+		for key, value := range containerTemplate.ObjectMeta.Annotations {
+			if strings.HasPrefix(key, apparmor.ContainerAnnotationKeyPrefix) || strings.HasPrefix(key, api.SeccompContainerAnnotationKeyPrefix) {
+				containerAnnotations[key] = value
+			} else {
+				podAnnotationKeys = append(podAnnotationKeys, key)
+			}
+		}
+		sort.Strings(podAnnotationKeys)
+		for _, key := range podAnnotationKeys {
+			log.Warningf("Unsupported %s annotation of service %q - ignoring, it is a container of the pod of service %q", key, containerName, name)
+		}
+
+		addContainer := func(template *api.PodTemplateSpec) error {
+			template.Spec.Containers = append(template.Spec.Containers, containerTemplate.Spec.Containers...)
+			// a volume shared with the service is already part of the pod
+			for _, volume := range containerTemplate.Spec.Volumes {
+				exists := false
+				for _, podVolume := range template.Spec.Volumes {
+					if podVolume.Name == volume.Name {
+						exists = true
+					}
+				}
+				if !exists {
+					template.Spec.Volumes = append(template.Spec.Volumes, volume)
+				}
+			}
+			for key, value := range containerAnnotations {
+				if _, ok := template.ObjectMeta.Annotations[key]; !ok {
+					if template.ObjectMeta.Annotations == nil {
+						template.ObjectMeta.Annotations = map[string]string{}
+					}
+					template.ObjectMeta.Annotations[key] = value
+				}
+			}
+			return nil
+		}
+		for _, obj := range *objects {
+			if err := k.UpdateController(obj, addContainer, func(meta *api.ObjectMeta) {}); err != nil {
+				return errors.Wrap(err, "k.UpdateController failed")
+			}
 		}
 	}
 	return nil
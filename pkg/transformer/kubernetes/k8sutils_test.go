@@ -31,6 +31,7 @@ import (
 	"github.com/pkg/errors"
 	"k8s.io/kubernetes/pkg/api"
 	"k8s.io/kubernetes/pkg/apis/extensions"
+	"k8s.io/kubernetes/pkg/runtime"
 )
 
 /*
@@ -411,6 +412,68 @@ func TestRecreateStrategyWithVolumesPresent(t *testing.T) {
 	}
 }
 
+// Tests if liveness and readiness probes are created from the health check
+func TestHealthCheck(t *testing.T) {
+	testCases := map[string]struct {
+		healthCheck kobject.HealthCheck
+		check       func(*api.Probe) bool
+	}{
+		"exec": {
+			kobject.HealthCheck{Test: []string{"echo", "foobar"}, Timeout: 1, Interval: 2, Retries: 3, StartPeriod: 4},
+			func(p *api.Probe) bool {
+				return p != nil && p.Exec != nil && reflect.DeepEqual(p.Exec.Command, []string{"echo", "foobar"}) &&
+					p.TimeoutSeconds == 1 && p.PeriodSeconds == 2 && p.FailureThreshold == 3 && p.InitialDelaySeconds == 4
+			},
+		},
+		"http": {
+			kobject.HealthCheck{Test: []string{"echo", "foobar"}, HTTPGetPath: "/health", HTTPGetPort: 8080},
+			func(p *api.Probe) bool {
+				return p != nil && p.HTTPGet != nil && p.HTTPGet.Path == "/health" && p.HTTPGet.Port.IntValue() == 8080
+			},
+		},
+		"tcp": {
+			kobject.HealthCheck{TCPPort: 6379},
+			func(p *api.Probe) bool {
+				return p != nil && p.TCPSocket != nil && p.TCPSocket.Port.IntValue() == 6379
+			},
+		},
+		"disabled": {
+			kobject.HealthCheck{Test: []string{"echo", "foobar"}, Disable: true},
+			func(p *api.Probe) bool { return p == nil },
+		},
+		"none": {
+			kobject.HealthCheck{},
+			func(p *api.Probe) bool { return p == nil },
+		},
+	}
+
+	for name, test := range testCases {
+		service := kobject.ServiceConfig{
+			ContainerName: "name",
+			Image:         "image",
+			HealthChecks:  test.healthCheck,
+		}
+
+		komposeObject := kobject.KomposeObject{
+			ServiceConfigs: map[string]kobject.ServiceConfig{"app": service},
+		}
+		k := Kubernetes{}
+
+		objects, err := k.Transform(komposeObject, kobject.ConvertOptions{CreateD: true, Replicas: 1})
+		if err != nil {
+			t.Error(errors.Wrap(err, "k.Transform failed"))
+		}
+		for _, obj := range objects {
+			if deployment, ok := obj.(*extensions.Deployment); ok {
+				container := deployment.Spec.Template.Spec.Containers[0]
+				if !test.check(container.LivenessProbe) || !test.check(container.ReadinessProbe) {
+					t.Errorf("Test case %q: unexpected probes %#v / %#v", name, container.LivenessProbe, container.ReadinessProbe)
+				}
+			}
+		}
+	}
+}
+
 func TestSortedKeys(t *testing.T) {
 	service := kobject.ServiceConfig{
 		ContainerName: "name",
@@ -457,3 +520,33 @@ func TestDurationStrToSecondsInt(t *testing.T) {
 		}
 	}
 }
+
+// Test that only the AppArmor and seccomp annotations of a container joining a pod are added to the pod
+func TestUpdatePodContainersAnnotations(t *testing.T) {
+	service := kobject.ServiceConfig{
+		Image: "nginx",
+		Containers: []kobject.ServiceConfig{
+			{
+				ContainerName: "app",
+				Image:         "app",
+				SecurityOpt:   []string{"apparmor=app"},
+				Sysctls:       map[string]string{"net.core.somaxconn": "1024"},
+				Placement:     kobject.Placement{Preferences: []string{"node.labels.zone"}},
+			},
+		},
+	}
+
+	k := Kubernetes{}
+	objects := []runtime.Object{k.InitD("web", service, 1)}
+	if err := k.UpdatePodContainers("web", service, &objects); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	template := objects[0].(*extensions.Deployment).Spec.Template
+	if len(template.Spec.Containers) != 2 || template.Spec.Containers[1].Name != "app" {
+		t.Fatalf("Expected the containers web and app, got %#v", template.Spec.Containers)
+	}
+	expected := map[string]string{"container.apparmor.security.beta.kubernetes.io/app": "localhost/app"}
+	if !reflect.DeepEqual(template.ObjectMeta.Annotations, expected) {
+		t.Errorf("Expected annotations %v, got %v", expected, template.ObjectMeta.Annotations)
+	}
+}
@@ -17,12 +17,15 @@ limitations under the License.
 package kubernetes
 
 import (
+	"encoding/json"
 	"fmt"
+	"path"
 	"reflect"
 	"strconv"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
+	"github.com/docker/go-units"
 	"github.com/fatih/structs"
 	"github.com/kubernetes/kompose/pkg/kobject"
 	"github.com/kubernetes/kompose/pkg/transformer"
@@ -43,7 +46,9 @@ import (
 	cmdutil "k8s.io/kubernetes/pkg/kubectl/cmd/util"
 
 	"k8s.io/kubernetes/pkg/runtime"
+	"k8s.io/kubernetes/pkg/security/apparmor"
 	"k8s.io/kubernetes/pkg/util/intstr"
+	"k8s.io/kubernetes/pkg/util/validation"
 	//"k8s.io/kubernetes/pkg/controller/daemon"
 	"github.com/pkg/errors"
 	"k8s.io/kubernetes/pkg/api/meta"
@@ -65,6 +70,45 @@ const TIMEOUT = 300
 // PVCRequestSize (Persistent Volume Claim) has default size
 const PVCRequestSize = "100Mi"
 
+// StorageClassAnnotation sets the storage class of a Persistent Volume Claim
+const StorageClassAnnotation = "volume.beta.kubernetes.io/storage-class"
+
+// WaitImage is the image of the init containers waiting for the dependencies of a service
+const WaitImage = "busybox"
+
+// NetworkLabelPrefix is the prefix of the pod labels of the networks a service is attached to
+const NetworkLabelPrefix = "io.kompose.network/"
+
+// TerminationGracePeriod is the grace period of the pods of Kubernetes, in seconds,
+// which the preStop hook of a stop_signal waits for without stop_grace_period
+const TerminationGracePeriod = 30
+
+// SubdomainLabel is the pod label selected by the headless Service of the domainname of a service
+const SubdomainLabel = "io.kompose.subdomain"
+
+// safeSysctls are the sysctls which the kubelet allows by default, the other namespaced
+// sysctls have to be allowed with its --experimental-allowed-unsafe-sysctls flag
+var safeSysctls = map[string]bool{
+	"kernel.shm_rmid_forced":       true,
+	"net.ipv4.ip_local_port_range": true,
+	"net.ipv4.tcp_syncookies":      true,
+}
+
+// namespacedSysctlPrefixes are the prefixes of the sysctls namespaced by the kernel,
+// the other sysctls would change the whole node and can't be set for a pod
+var namespacedSysctlPrefixes = []string{"kernel.shm", "kernel.msg", "kernel.sem", "fs.mqueue.", "net."}
+
+// MasterNodeLabel is the label of the master nodes, which are the managers of a swarm
+const MasterNodeLabel = "node-role.kubernetes.io/master"
+
+// placementNodeLabels are the labels of the Kubernetes nodes matching the node attributes
+// of a placement, other than node.labels.* which match the node labels themselves
+var placementNodeLabels = map[string]string{
+	"node.hostname":      "kubernetes.io/hostname",
+	"node.platform.os":   "beta.kubernetes.io/os",
+	"node.platform.arch": "beta.kubernetes.io/arch",
+}
+
 // CheckUnsupportedKey checks if given komposeObject contains
 // keys that are not supported by this transformer.
 // list of all unsupported keys are stored in unsupportedKey variable
@@ -238,9 +282,13 @@ func (k *Kubernetes) initIngress(name string, service kobject.ServiceConfig, por
 	return ingress
 }
 
-// CreatePVC initializes PersistentVolumeClaim
-func (k *Kubernetes) CreatePVC(name string, mode string) (*api.PersistentVolumeClaim, error) {
-	size, err := resource.ParseQuantity(PVCRequestSize)
+// CreatePVC initializes the PersistentVolumeClaim of a volume
+func (k *Kubernetes) CreatePVC(volume kobject.Volumes) (*api.PersistentVolumeClaim, error) {
+	requestSize := PVCRequestSize
+	if volume.PVCSize != "" {
+		requestSize = volume.PVCSize
+	}
+	size, err := resource.ParseQuantity(requestSize)
 	if err != nil {
 		return nil, errors.Wrap(err, "resource.ParseQuantity failed, Error parsing size")
 	}
@@ -251,8 +299,8 @@ func (k *Kubernetes) CreatePVC(name string, mode string) (*api.PersistentVolumeC
 			APIVersion: "v1",
 		},
 		ObjectMeta: api.ObjectMeta{
-			Name:   name,
-			Labels: transformer.ConfigLabels(name),
+			Name:   volume.PVCName,
+			Labels: transformer.ConfigLabels(volume.PVCName),
 		},
 		Spec: api.PersistentVolumeClaimSpec{
 			Resources: api.ResourceRequirements{
@@ -263,7 +311,14 @@ func (k *Kubernetes) CreatePVC(name string, mode string) (*api.PersistentVolumeC
 		},
 	}
 
-	if mode == "ro" {
+	// this version of the API has no storageClassName field, the beta annotation is used instead
+	if volume.StorageClass != "" {
+		pvc.ObjectMeta.Annotations = map[string]string{StorageClassAnnotation: volume.StorageClass}
+	}
+
+	if volume.AccessMode != "" {
+		pvc.Spec.AccessModes = []api.PersistentVolumeAccessMode{api.PersistentVolumeAccessMode(volume.AccessMode)}
+	} else if volume.Mode == "ro" {
 		pvc.Spec.AccessModes = []api.PersistentVolumeAccessMode{api.ReadOnlyMany}
 	} else {
 		pvc.Spec.AccessModes = []api.PersistentVolumeAccessMode{api.ReadWriteOnce}
@@ -271,20 +326,440 @@ func (k *Kubernetes) CreatePVC(name string, mode string) (*api.PersistentVolumeC
 	return pvc, nil
 }
 
+// InitConfigMap initializes a ConfigMap holding the variables of an env_file
+func (k *Kubernetes) InitConfigMap(envFile kobject.EnvFile) *api.ConfigMap {
+	data := make(map[string]string)
+	for _, env := range envFile.Environment {
+		data[env.Name] = env.Value
+	}
+
+	configMap := &api.ConfigMap{
+		TypeMeta: unversioned.TypeMeta{
+			Kind:       "ConfigMap",
+			APIVersion: "v1",
+		},
+		ObjectMeta: api.ObjectMeta{
+			Name:   envFile.Name,
+			Labels: transformer.ConfigLabels(envFile.Name),
+		},
+		Data: data,
+	}
+	return configMap
+}
+
+// InitSecret initializes a Secret holding the content of a secret file, under a key named after the Secret
+func (k *Kubernetes) InitSecret(secret kobject.FileObject) *api.Secret {
+	return &api.Secret{
+		TypeMeta: unversioned.TypeMeta{
+			Kind:       "Secret",
+			APIVersion: "v1",
+		},
+		ObjectMeta: api.ObjectMeta{
+			Name:   secret.Name,
+			Labels: transformer.ConfigLabels(secret.Name),
+		},
+		Type: api.SecretTypeOpaque,
+		Data: map[string][]byte{secret.Name: secret.Data},
+	}
+}
+
+// InitConfigConfigMap initializes a ConfigMap holding the content of a config file, under a key named after the ConfigMap
+func (k *Kubernetes) InitConfigConfigMap(config kobject.FileObject) *api.ConfigMap {
+	return &api.ConfigMap{
+		TypeMeta: unversioned.TypeMeta{
+			Kind:       "ConfigMap",
+			APIVersion: "v1",
+		},
+		ObjectMeta: api.ObjectMeta{
+			Name:   config.Name,
+			Labels: transformer.ConfigLabels(config.Name),
+		},
+		Data: map[string]string{config.Name: string(config.Data)},
+	}
+}
+
+// referencedFileObjects returns the sorted names of the secrets or configs in objects which are
+// referenced by a service, and which are not external. references returns the references of a service.
+func referencedFileObjects(komposeObject kobject.KomposeObject, objects map[string]kobject.FileObject, references func(kobject.ServiceConfig) []kobject.FileReference) []string {
+	referenced := map[string]bool{}
+	for _, service := range komposeObject.ServiceConfigs {
+		for _, reference := range references(service) {
+			referenced[reference.Source] = true
+		}
+	}
+	var names []string
+	for name, object := range objects {
+		if referenced[name] && !object.External {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CreateSecrets initializes the Secrets referenced by the services, external Secrets already exist
+func (k *Kubernetes) CreateSecrets(komposeObject kobject.KomposeObject) []runtime.Object {
+	var objects []runtime.Object
+	secretReferences := func(service kobject.ServiceConfig) []kobject.FileReference { return service.Secrets }
+	for _, name := range referencedFileObjects(komposeObject, komposeObject.Secrets, secretReferences) {
+		objects = append(objects, k.InitSecret(komposeObject.Secrets[name]))
+	}
+	return objects
+}
+
+// CreateConfigConfigMaps initializes the ConfigMaps of the configs referenced by the services,
+// external ConfigMaps already exist
+func (k *Kubernetes) CreateConfigConfigMaps(komposeObject kobject.KomposeObject) []runtime.Object {
+	var objects []runtime.Object
+	configReferences := func(service kobject.ServiceConfig) []kobject.FileReference { return service.Configs }
+	for _, name := range referencedFileObjects(komposeObject, komposeObject.Configs, configReferences) {
+		objects = append(objects, k.InitConfigConfigMap(komposeObject.Configs[name]))
+	}
+	return objects
+}
+
+// ConfigNetworkLabels configures the labels of the networks a service is attached to,
+// which are selected by the NetworkPolicies of the networks
+func ConfigNetworkLabels(service kobject.ServiceConfig) map[string]string {
+	labels := map[string]string{}
+	for _, network := range service.Network {
+		labels[NetworkLabelPrefix+network] = "true"
+	}
+	return labels
+}
+
+// CreateSubdomainServices initializes a headless Service named after each domainname of the services,
+// which selects their pods so that the FQDN of the pods (hostname.subdomain) resolves
+func (k *Kubernetes) CreateSubdomainServices(komposeObject kobject.KomposeObject) ([]runtime.Object, error) {
+	subdomains := map[string]bool{}
+	for name, service := range komposeObject.ServiceConfigs {
+		if service.DomainName == "" {
+			continue
+		}
+		if _, ok := komposeObject.ServiceConfigs[service.DomainName]; ok {
+			return nil, fmt.Errorf("domainname %q of service %q is also the name of a service, their Services would conflict", service.DomainName, name)
+		}
+		subdomains[service.DomainName] = true
+	}
+
+	var sortedSubdomains []string
+	for subdomain := range subdomains {
+		sortedSubdomains = append(sortedSubdomains, subdomain)
+	}
+	sort.Strings(sortedSubdomains)
+
+	var objects []runtime.Object
+	for _, subdomain := range sortedSubdomains {
+		svc := &api.Service{
+			TypeMeta: unversioned.TypeMeta{
+				Kind:       "Service",
+				APIVersion: "v1",
+			},
+			ObjectMeta: api.ObjectMeta{
+				Name:   subdomain,
+				Labels: transformer.ConfigLabels(subdomain),
+			},
+			Spec: api.ServiceSpec{
+				Selector:  map[string]string{SubdomainLabel: subdomain},
+				ClusterIP: "None",
+				// Configure a dummy port: https://github.com/kubernetes/kubernetes/issues/32766.
+				Ports: []api.ServicePort{
+					{
+						Name: "headless",
+						Port: 55555,
+					},
+				},
+			},
+		}
+		objects = append(objects, svc)
+	}
+	return objects, nil
+}
+
+// CreateLinkServices initializes a Service named after each alias of links differing from the name of
+// the linked service, which selects its pods, and an ExternalName Service named after each alias of external_links.
+// The DNS name of an external container is set with a label, or is the container name in the --external-links-domain
+// domain. Aliases which would conflict with the other Services are reported and ignored.
+func (k *Kubernetes) CreateLinkServices(komposeObject kobject.KomposeObject, opt kobject.ConvertOptions) []runtime.Object {
+	reserved := map[string]bool{}
+	for name, service := range komposeObject.ServiceConfigs {
+		reserved[name] = true
+		if service.DomainName != "" {
+			reserved[service.DomainName] = true
+		}
+	}
+	// the target of each alias, to ignore the aliases used by several services for different targets
+	aliases := map[string]string{}
+	addAlias := func(name string, key string, alias string, target string) bool {
+		if reserved[alias] {
+			log.Warningf("Unsupported alias %q of %s of service %q - ignoring, it is the name of another Service", alias, key, name)
+			return false
+		}
+		if previous, ok := aliases[alias]; ok {
+			if previous != target {
+				log.Warningf("Unsupported alias %q of %s of service %q - ignoring, it is already an alias of %s", alias, key, name, previous)
+			}
+			return false
+		}
+		aliases[alias] = target
+		return true
+	}
+
+	var objects []runtime.Object
+	for _, name := range SortedKeys(komposeObject) {
+		service := komposeObject.ServiceConfigs[name]
+		for _, link := range service.Links {
+			if link.Alias == link.Service {
+				continue
+			}
+			target, ok := komposeObject.ServiceConfigs[link.Service]
+			if !ok {
+				log.Warningf("Unsupported link %q of service %q - ignoring, there is no such service", link.Service, name)
+				continue
+			}
+			if !addAlias(name, "links", link.Alias, link.Service) {
+				continue
+			}
+
+			svc := k.InitSvc(link.Service, target)
+			svc.ObjectMeta.Name = link.Alias
+			svc.ObjectMeta.Labels = transformer.ConfigLabels(link.Alias)
+			svc.Spec.Ports = k.ConfigServicePorts(link.Service, target)
+			if len(svc.Spec.Ports) == 0 {
+				// Configure a dummy port: https://github.com/kubernetes/kubernetes/issues/32766.
+				svc.Spec.Ports = []api.ServicePort{{Name: "headless", Port: 55555}}
+				svc.Spec.ClusterIP = "None"
+			}
+			objects = append(objects, svc)
+		}
+
+		for _, link := range service.ExternalLinks {
+			externalName := link.ExternalName
+			if externalName == "" {
+				externalName = strings.ToLower(strings.Replace(link.Service, "_", "-", -1))
+				if opt.ExternalLinksDomain != "" {
+					externalName += "." + opt.ExternalLinksDomain
+				} else {
+					log.Warningf("The Service of external link %q of service %q points to %q, set its DNS name with the kompose.service.external_link.%s label or --external-links-domain", link.Service, name, externalName, link.Alias)
+				}
+			}
+			if len(validation.IsDNS1123Subdomain(externalName)) > 0 {
+				log.Warningf("Unsupported external link %q of service %q - ignoring, %q is not a valid DNS name", link.Service, name, externalName)
+				continue
+			}
+			if !addAlias(name, "external_links", link.Alias, externalName) {
+				continue
+			}
+
+			svc := &api.Service{
+				TypeMeta: unversioned.TypeMeta{
+					Kind:       "Service",
+					APIVersion: "v1",
+				},
+				ObjectMeta: api.ObjectMeta{
+					Name:   link.Alias,
+					Labels: transformer.ConfigLabels(link.Alias),
+				},
+				Spec: api.ServiceSpec{
+					Type:         api.ServiceTypeExternalName,
+					ExternalName: externalName,
+				},
+			}
+			objects = append(objects, svc)
+		}
+	}
+	return objects
+}
+
+// CreateNetworkPolicy initializes the NetworkPolicy of a network. It allows ingress to the pods
+// attached to the network from the other pods attached to it. Unless the network is internal,
+// the published ports of the services attached to it are reachable from anywhere too.
+func (k *Kubernetes) CreateNetworkPolicy(networkName string, network kobject.NetworkConfig, komposeObject kobject.KomposeObject) *extensions.NetworkPolicy {
+	selector := map[string]string{NetworkLabelPrefix + networkName: "true"}
+	ingress := []extensions.NetworkPolicyIngressRule{
+		{
+			From: []extensions.NetworkPolicyPeer{
+				{PodSelector: &unversioned.LabelSelector{MatchLabels: selector}},
+			},
+		},
+	}
+
+	if !network.Internal {
+		var ports []extensions.NetworkPolicyPort
+		seen := map[string]bool{}
+		for _, name := range SortedKeys(komposeObject) {
+			service := komposeObject.ServiceConfigs[name]
+			if _, ok := ConfigNetworkLabels(service)[NetworkLabelPrefix+networkName]; !ok {
+				continue
+			}
+			for _, port := range service.Port {
+				if port.HostPort == 0 || seen[fmt.Sprint(port.ContainerPort, port.Protocol)] {
+					continue
+				}
+				seen[fmt.Sprint(port.ContainerPort, port.Protocol)] = true
+				policyPort := extensions.NetworkPolicyPort{}
+				targetPort := intstr.FromInt(int(port.ContainerPort))
+				policyPort.Port = &targetPort
+				// If the default is already TCP, no need to include it.
+				if port.Protocol != "" && port.Protocol != api.ProtocolTCP {
+					protocol := port.Protocol
+					policyPort.Protocol = &protocol
+				}
+				ports = append(ports, policyPort)
+			}
+		}
+		if len(ports) > 0 {
+			ingress = append(ingress, extensions.NetworkPolicyIngressRule{Ports: ports})
+		}
+	}
+
+	return &extensions.NetworkPolicy{
+		TypeMeta: unversioned.TypeMeta{
+			Kind:       "NetworkPolicy",
+			APIVersion: "extensions/v1beta1",
+		},
+		ObjectMeta: api.ObjectMeta{
+			Name:   networkName,
+			Labels: transformer.ConfigLabels(networkName),
+		},
+		Spec: extensions.NetworkPolicySpec{
+			PodSelector: unversioned.LabelSelector{MatchLabels: selector},
+			Ingress:     ingress,
+		},
+	}
+}
+
+// CreateNetworkPolicies initializes the NetworkPolicies of the networks the services are attached to
+func (k *Kubernetes) CreateNetworkPolicies(komposeObject kobject.KomposeObject) []runtime.Object {
+	var networkNames []string
+	seen := map[string]bool{}
+	for _, service := range komposeObject.ServiceConfigs {
+		for _, network := range service.Network {
+			if !seen[network] {
+				seen[network] = true
+				networkNames = append(networkNames, network)
+			}
+		}
+	}
+	sort.Strings(networkNames)
+
+	var objects []runtime.Object
+	for _, networkName := range networkNames {
+		objects = append(objects, k.CreateNetworkPolicy(networkName, komposeObject.NetworkConfigs[networkName], komposeObject))
+	}
+	return objects
+}
+
+// UsesNetworks returns true if a service is attached to a network other than the default one
+func (k *Kubernetes) UsesNetworks(komposeObject kobject.KomposeObject) bool {
+	for _, service := range komposeObject.ServiceConfigs {
+		for _, network := range service.Network {
+			if network != "default" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// waitCheck returns the shell command checking that a dependency accepts connections through its Service.
+// Services only route to ready pods, so when the dependency has a readiness probe, the check also waits for
+// the dependency to be ready. For service_healthy, an HTTP GET health check is checked directly.
+func (k *Kubernetes) waitCheck(dependency kobject.ServiceDependency, dependencyService kobject.ServiceConfig) string {
+	servicePorts := k.ConfigServicePorts(dependency.Service, dependencyService)
+	if dependency.Condition == kobject.ServiceHealthy && dependencyService.HealthChecks.HTTPGetPort != 0 {
+		for _, port := range servicePorts {
+			if port.TargetPort.IntVal == dependencyService.HealthChecks.HTTPGetPort {
+				return fmt.Sprintf("wget -q -O /dev/null http://%s:%d%s", dependency.Service, port.Port, dependencyService.HealthChecks.HTTPGetPath)
+			}
+		}
+	}
+	// the headless Service of a dependency without ports only resolves once a pod is ready
+	if len(servicePorts) == 0 {
+		return fmt.Sprintf("nslookup %s", dependency.Service)
+	}
+	return fmt.Sprintf("nc -z %s %d", dependency.Service, servicePorts[0].Port)
+}
+
+// InitWaitContainers initializes an init container for each dependency of a service,
+// which waits until the Service of the dependency accepts connections
+func (k *Kubernetes) InitWaitContainers(name string, service kobject.ServiceConfig, komposeObject kobject.KomposeObject) ([]api.Container, error) {
+	var containers []api.Container
+	for _, dependency := range service.DependsOn {
+		dependencyService, ok := komposeObject.ServiceConfigs[dependency.Service]
+		if !ok {
+			return nil, fmt.Errorf("service %q depends on undefined service %q", name, dependency.Service)
+		}
+		if dependencyService.Restart == "no" || dependencyService.Restart == "on-failure" {
+			log.Warningf("Service %q can't wait for %q, which is converted to a Pod without Service", name, dependency.Service)
+			continue
+		}
+		if dependency.Condition == kobject.ServiceHealthy && k.ConfigProbe(dependencyService.HealthChecks) == nil {
+			log.Warningf("Service %q waits for %q to be healthy, but %q has no health check, waiting for it to start instead", name, dependency.Service, dependency.Service)
+		}
+
+		check := k.waitCheck(dependency, dependencyService)
+		containers = append(containers, api.Container{
+			Name:    "wait-for-" + dependency.Service,
+			Image:   WaitImage,
+			Command: []string{"sh", "-c", fmt.Sprintf("until %s; do echo waiting for %s; sleep 2; done", check, dependency.Service)},
+		})
+	}
+	return containers, nil
+}
+
+// UpdateInitContainers adds the init containers waiting for the dependencies of a service to the pods of objects
+func (k *Kubernetes) UpdateInitContainers(name string, service kobject.ServiceConfig, komposeObject kobject.KomposeObject, objects []runtime.Object) error {
+	initContainers, err := k.InitWaitContainers(name, service, komposeObject)
+	if err != nil || len(initContainers) == 0 {
+		return err
+	}
+	fillTemplate := func(template *api.PodTemplateSpec) error {
+		template.Spec.InitContainers = initContainers
+		return nil
+	}
+	for _, obj := range objects {
+		if err := k.UpdateController(obj, fillTemplate, func(*api.ObjectMeta) {}); err != nil {
+			return errors.Wrap(err, "k.UpdateController failed")
+		}
+	}
+	return nil
+}
+
+// IgnoresDependsOn returns true if a service has dependencies, but doesn't wait for them
+func (k *Kubernetes) IgnoresDependsOn(komposeObject kobject.KomposeObject, opt kobject.ConvertOptions) bool {
+	for _, service := range komposeObject.ServiceConfigs {
+		if len(service.DependsOn) > 0 && !opt.WaitForDependencies && !service.WaitForDependencies {
+			return true
+		}
+	}
+	return false
+}
+
 // ConfigPorts configures the container ports.
+// The ports published in host mode are host ports of the container, instead of Service ports.
 func (k *Kubernetes) ConfigPorts(name string, service kobject.ServiceConfig) []api.ContainerPort {
 	ports := []api.ContainerPort{}
 	for _, port := range service.Port {
+		var hostPort int32
+		if port.Mode == kobject.PortModeHost {
+			hostPort = port.HostPort
+			if hostPort == 0 {
+				hostPort = port.ContainerPort
+			}
+		}
 
 		// If the default is already TCP, no need to include it.
 		if port.Protocol == api.ProtocolTCP {
 			ports = append(ports, api.ContainerPort{
 				ContainerPort: port.ContainerPort,
+				HostPort:      hostPort,
 				HostIP:        port.HostIP,
 			})
 		} else {
 			ports = append(ports, api.ContainerPort{
 				ContainerPort: port.ContainerPort,
+				HostPort:      hostPort,
 				Protocol:      port.Protocol,
 				HostIP:        port.HostIP,
 			})
@@ -295,10 +770,25 @@ func (k *Kubernetes) ConfigPorts(name string, service kobject.ServiceConfig) []a
 	return ports
 }
 
+// PodPorts returns the ports of all the containers of the pod of a service which are exposed by its Service,
+// the ports published in host mode are not
+func PodPorts(service kobject.ServiceConfig) []kobject.Ports {
+	var ports []kobject.Ports
+	for _, container := range append([]kobject.ServiceConfig{service}, service.Containers...) {
+		for _, port := range container.Port {
+			if port.Mode != kobject.PortModeHost {
+				ports = append(ports, port)
+			}
+		}
+	}
+	return ports
+}
+
 // ConfigServicePorts configure the container service ports.
+// The Service of a pod with several containers exposes the ports of all of them.
 func (k *Kubernetes) ConfigServicePorts(name string, service kobject.ServiceConfig) []api.ServicePort {
 	servicePorts := []api.ServicePort{}
-	for _, port := range service.Port {
+	for _, port := range PodPorts(service) {
 		if port.HostPort == 0 {
 			port.HostPort = port.ContainerPort
 		}
@@ -342,20 +832,368 @@ func (k *Kubernetes) ConfigCapabilities(service kobject.ServiceConfig) *api.Capa
 	}
 }
 
-// ConfigTmpfs configure the tmpfs.
+// ConfigSecurityOpts configures the SELinux options of the container of a service, and the pod
+// annotations setting its AppArmor and seccomp profiles, from its security_opt key
+func (k *Kubernetes) ConfigSecurityOpts(name string, service kobject.ServiceConfig) (*api.SELinuxOptions, map[string]string) {
+	containerName := name
+	if service.ContainerName != "" {
+		containerName = service.ContainerName
+	}
+
+	var seLinuxOptions *api.SELinuxOptions
+	annotations := map[string]string{}
+	for _, securityOpt := range service.SecurityOpt {
+		// Docker accepts both "key=value" and the older "key:value"
+		parts := strings.SplitN(securityOpt, "=", 2)
+		if len(parts) == 1 {
+			parts = strings.SplitN(securityOpt, ":", 2)
+		}
+		value := ""
+		if len(parts) == 2 {
+			value = parts[1]
+		}
+
+		switch parts[0] {
+		case "label":
+			label := strings.SplitN(value, ":", 2)
+			if len(label) != 2 || (label[0] != "user" && label[0] != "role" && label[0] != "type" && label[0] != "level") {
+				log.Warningf("Unsupported security_opt %q of service %q - ignoring, only the user, role, type and level SELinux labels can be set", securityOpt, name)
+				continue
+			}
+			if seLinuxOptions == nil {
+				seLinuxOptions = &api.SELinuxOptions{}
+			}
+			switch label[0] {
+			case "user":
+				seLinuxOptions.User = label[1]
+			case "role":
+				seLinuxOptions.Role = label[1]
+			case "type":
+				seLinuxOptions.Type = label[1]
+			case "level":
+				seLinuxOptions.Level = label[1]
+			}
+		case "apparmor":
+			switch value {
+			case "unconfined":
+				log.Warningf("Unsupported security_opt %q of service %q - ignoring, the AppArmor annotation of a container has no unconfined profile", securityOpt, name)
+			case "docker-default":
+				annotations[apparmor.ContainerAnnotationKeyPrefix+containerName] = apparmor.ProfileRuntimeDefault
+			default:
+				// the profile has to be loaded on the nodes
+				annotations[apparmor.ContainerAnnotationKeyPrefix+containerName] = apparmor.ProfileNamePrefix + value
+			}
+		case "seccomp":
+			if value == "unconfined" {
+				annotations[api.SeccompContainerAnnotationKeyPrefix+containerName] = value
+				continue
+			}
+			// Docker takes the path of the profile, the kubelets look for it in their --seccomp-profile-root directory
+			profile := path.Base(value)
+			annotations[api.SeccompContainerAnnotationKeyPrefix+containerName] = "localhost/" + profile
+			log.Warningf("Seccomp profile %s of service %q has to be copied to the --seccomp-profile-root directory of the kubelets", profile, name)
+		case "no-new-privileges":
+			if value == "" || value == "true" {
+				log.Warningf("Unsupported security_opt %q of service %q - ignoring, allowPrivilegeEscalation is not supported by this client", securityOpt, name)
+			}
+		default:
+			log.Warningf("Unsupported security_opt %q of service %q - ignoring", securityOpt, name)
+		}
+	}
+	return seLinuxOptions, annotations
+}
+
+// ConfigSysctls configures the pod annotations setting the namespaced sysctls of a service
+func (k *Kubernetes) ConfigSysctls(name string, service kobject.ServiceConfig) map[string]string {
+	var sysctlNames []string
+	for sysctlName := range service.Sysctls {
+		sysctlNames = append(sysctlNames, sysctlName)
+	}
+	sort.Strings(sysctlNames)
+
+	var safe, unsafe []api.Sysctl
+	for _, sysctlName := range sysctlNames {
+		sysctl := api.Sysctl{Name: sysctlName, Value: service.Sysctls[sysctlName]}
+		namespaced := false
+		for _, prefix := range namespacedSysctlPrefixes {
+			if strings.HasPrefix(sysctlName, prefix) {
+				namespaced = true
+			}
+		}
+		switch {
+		case !namespaced:
+			log.Warningf("Unsupported sysctl %s of service %q - ignoring, it isn't namespaced and would change the whole node", sysctlName, name)
+		case safeSysctls[sysctlName]:
+			safe = append(safe, sysctl)
+		default:
+			unsafe = append(unsafe, sysctl)
+		}
+	}
+
+	annotations := map[string]string{}
+	if len(safe) > 0 {
+		annotations[api.SysctlsPodAnnotationKey] = api.PodAnnotationsFromSysctls(safe)
+	}
+	if len(unsafe) > 0 {
+		annotations[api.UnsafeSysctlsPodAnnotationKey] = api.PodAnnotationsFromSysctls(unsafe)
+		log.Warningf("Sysctls %s of service %q are unsafe, the kubelets have to allow them with --experimental-allowed-unsafe-sysctls", api.PodAnnotationsFromSysctls(unsafe), name)
+	}
+	return annotations
+}
+
+// placementNodeLabel returns the label of the Kubernetes nodes matching a node attribute of a placement
+func placementNodeLabel(attribute string) (string, bool) {
+	if strings.HasPrefix(attribute, "node.labels.") {
+		return strings.TrimPrefix(attribute, "node.labels."), true
+	}
+	label, ok := placementNodeLabels[attribute]
+	return label, ok
+}
+
+// ConfigPlacement configures the nodeSelector and the affinity annotation of the pods of a service
+// from its placement. The "==" constraints are node selectors, the other constraints are required
+// node affinities, and the spread preferences are preferred pod anti-affinities.
+// A node label or a value which isn't a valid Kubernetes label is an error.
+func (k *Kubernetes) ConfigPlacement(name string, service kobject.ServiceConfig) (map[string]string, map[string]string, error) {
+	nodeSelector := map[string]string{}
+	var requirements []api.NodeSelectorRequirement
+	for _, constraint := range service.Placement.Constraints {
+		if constraint.Key == "node.role" {
+			// only the master nodes have the master label
+			operator := api.NodeSelectorOpExists
+			if (constraint.Value == "manager") != (constraint.Operator == "==") {
+				operator = api.NodeSelectorOpDoesNotExist
+			}
+			requirements = append(requirements, api.NodeSelectorRequirement{Key: MasterNodeLabel, Operator: operator})
+			continue
+		}
+
+		label, ok := placementNodeLabel(constraint.Key)
+		if !ok {
+			log.Warningf("Unsupported placement constraint %s %s %s of service %q - ignoring, Kubernetes nodes have no matching label", constraint.Key, constraint.Operator, constraint.Value, name)
+			continue
+		}
+		if errs := append(validation.IsQualifiedName(label), validation.IsValidLabelValue(constraint.Value)...); len(errs) > 0 {
+			return nil, nil, fmt.Errorf("invalid placement constraint %s %s %s of service %q: %s", constraint.Key, constraint.Operator, constraint.Value, name, strings.Join(errs, ", "))
+		}
+		if value, ok := nodeSelector[label]; constraint.Operator == "==" && (!ok || value == constraint.Value) {
+			nodeSelector[label] = constraint.Value
+			continue
+		}
+		operator := api.NodeSelectorOpIn
+		if constraint.Operator == "!=" {
+			operator = api.NodeSelectorOpNotIn
+		}
+		requirements = append(requirements, api.NodeSelectorRequirement{Key: label, Operator: operator, Values: []string{constraint.Value}})
+	}
+
+	var preferences []api.WeightedPodAffinityTerm
+	for _, spread := range service.Placement.Preferences {
+		label, ok := placementNodeLabel(spread)
+		if !ok {
+			log.Warningf("Unsupported placement preference spread %s of service %q - ignoring, Kubernetes nodes have no matching label", spread, name)
+			continue
+		}
+		if errs := validation.IsQualifiedName(label); len(errs) > 0 {
+			return nil, nil, fmt.Errorf("invalid placement preference spread %s of service %q: %s", spread, name, strings.Join(errs, ", "))
+		}
+		// the pods of the service avoid the nodes with the same label value as the nodes running the other pods
+		preferences = append(preferences, api.WeightedPodAffinityTerm{
+			Weight: 100,
+			PodAffinityTerm: api.PodAffinityTerm{
+				LabelSelector: &unversioned.LabelSelector{MatchLabels: transformer.ConfigLabels(name)},
+				TopologyKey:   label,
+			},
+		})
+	}
+
+	affinity := api.Affinity{}
+	if len(requirements) > 0 {
+		affinity.NodeAffinity = &api.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &api.NodeSelector{
+				NodeSelectorTerms: []api.NodeSelectorTerm{{MatchExpressions: requirements}},
+			},
+		}
+	}
+	if len(preferences) > 0 {
+		affinity.PodAntiAffinity = &api.PodAntiAffinity{PreferredDuringSchedulingIgnoredDuringExecution: preferences}
+	}
+
+	// the affinity is an alpha feature, set with an annotation of the pod
+	annotations := map[string]string{}
+	if affinity.NodeAffinity != nil || affinity.PodAntiAffinity != nil {
+		data, err := json.Marshal(affinity)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "unable to marshal the affinity of service %s", name)
+		}
+		annotations[api.AffinityAnnotationKey] = string(data)
+	}
+	return nodeSelector, annotations, nil
+}
+
+// ConfigRollingUpdate configures the rolling update of the pods of a service from its update_config,
+// and the minimum number of seconds a new pod is ready before the next ones are updated.
+// The update_config and rollback_config settings which can't be converted are reported.
+func (k *Kubernetes) ConfigRollingUpdate(name string, service kobject.ServiceConfig) (*extensions.RollingUpdateDeployment, int32) {
+	if service.RollbackConfig != nil {
+		log.Warningf("Unsupported deploy.rollback_config key of service %q - ignoring, Kubernetes doesn't roll back a failed rollout automatically", name)
+	}
+
+	updateConfig := service.UpdateConfig
+	if updateConfig == nil {
+		return nil, 0
+	}
+	if service.DeployMode == "global" {
+		log.Warningf("Unsupported deploy.update_config key of service %q - ignoring, DaemonSets have no rolling update strategy", name)
+		return nil, 0
+	}
+	if len(service.Volumes) > 0 {
+		log.Warningf("Unsupported deploy.update_config key of service %q - ignoring, the pods of a service with volumes are recreated", name)
+		return nil, 0
+	}
+
+	var unsupported []string
+	if updateConfig.Monitor != 0 {
+		unsupported = append(unsupported, "monitor")
+	}
+	if updateConfig.FailureAction != "" {
+		unsupported = append(unsupported, "failure_action")
+	}
+	if updateConfig.MaxFailureRatio != 0 {
+		unsupported = append(unsupported, "max_failure_ratio")
+	}
+	if len(unsupported) > 0 {
+		log.Warningf("Unsupported deploy.update_config %s of service %q - ignoring, Kubernetes doesn't watch a rollout for failures", strings.Join(unsupported, ", "), name)
+	}
+
+	// all the pods are updated at once when parallelism is 0
+	pods := intstr.FromString("100%")
+	if updateConfig.Parallelism > 0 {
+		pods = intstr.FromInt(int(updateConfig.Parallelism))
+	}
+	// stop-first stops the old pods before starting the new ones, start-first starts the new ones first
+	rollingUpdate := &extensions.RollingUpdateDeployment{MaxUnavailable: pods, MaxSurge: intstr.FromInt(0)}
+	if updateConfig.Order == "start-first" {
+		rollingUpdate = &extensions.RollingUpdateDeployment{MaxUnavailable: intstr.FromInt(0), MaxSurge: pods}
+	}
+	return rollingUpdate, updateConfig.Delay
+}
+
+// ConfigCommand configures the command and the arguments of the container of a service.
+// Kubernetes can't set ulimits, but when WrapUlimitNofile is set, the nofile ulimit is set
+// by a shell wrapping the entrypoint. The entrypoint of the image isn't known, so a service
+// without entrypoint isn't wrapped.
+func (k *Kubernetes) ConfigCommand(name string, service kobject.ServiceConfig) ([]string, []string) {
+	command, args := service.Command, service.Args
+	for _, ulimit := range service.Ulimits {
+		if ulimit.Name != "nofile" {
+			log.Warningf("Unsupported ulimit %s (soft %d, hard %d) of service %q - ignoring, Kubernetes can't set ulimits", ulimit.Name, ulimit.Soft, ulimit.Hard, name)
+			continue
+		}
+		if !service.WrapUlimitNofile {
+			log.Warningf("Unsupported ulimit nofile (soft %d, hard %d) of service %q - ignoring, Kubernetes can't set ulimits, use the kompose.service.wrap_ulimit_nofile label to set it in a shell wrapping the command", ulimit.Soft, ulimit.Hard, name)
+			continue
+		}
+		if len(command) == 0 {
+			log.Warningf("Unsupported ulimit nofile of service %q - ignoring, the entrypoint of the image can't be wrapped, set the entrypoint of the service to wrap it", name)
+			continue
+		}
+
+		// the shell execs its arguments ("$0" "$@"), which are the command and the arguments of the container
+		script := fmt.Sprintf(`ulimit -Hn %d && ulimit -Sn %d; exec "$0" "$@"`, ulimit.Hard, ulimit.Soft)
+		command = append([]string{"sh", "-c", script}, command...)
+	}
+	return command, args
+}
+
+// ConfigProbe configures a liveness or readiness probe from the service health check.
+// returns nil if the service has no health check or it is disabled
+func (k *Kubernetes) ConfigProbe(healthCheck kobject.HealthCheck) *api.Probe {
+	if healthCheck.Disable {
+		return nil
+	}
+
+	probe := api.Probe{
+		TimeoutSeconds:      healthCheck.Timeout,
+		PeriodSeconds:       healthCheck.Interval,
+		FailureThreshold:    healthCheck.Retries,
+		InitialDelaySeconds: healthCheck.StartPeriod,
+	}
+
+	// kompose labels take precedence over the compose test command
+	switch {
+	case healthCheck.HTTPGetPort != 0:
+		probe.Handler = api.Handler{
+			HTTPGet: &api.HTTPGetAction{
+				Path: healthCheck.HTTPGetPath,
+				Port: intstr.FromInt(int(healthCheck.HTTPGetPort)),
+			},
+		}
+	case healthCheck.TCPPort != 0:
+		probe.Handler = api.Handler{
+			TCPSocket: &api.TCPSocketAction{
+				Port: intstr.FromInt(int(healthCheck.TCPPort)),
+			},
+		}
+	case len(healthCheck.Test) > 0:
+		probe.Handler = api.Handler{
+			Exec: &api.ExecAction{
+				Command: healthCheck.Test,
+			},
+		}
+	default:
+		return nil
+	}
+	return &probe
+}
+
+// ConfigStopSignal configures a preStop hook sending the stop_signal of the service to the process of the container,
+// as Kubernetes stops containers with SIGTERM, and waiting for the process to exit during the grace period.
+// returns nil if the service has no stop_signal or it is SIGTERM
+func (k *Kubernetes) ConfigStopSignal(service kobject.ServiceConfig, gracePeriod *int64) *api.Lifecycle {
+	if service.StopSignal == "" || service.StopSignal == "TERM" {
+		return nil
+	}
+
+	seconds := int64(TerminationGracePeriod)
+	if gracePeriod != nil {
+		seconds = *gracePeriod
+	}
+	script := fmt.Sprintf("kill -%s 1 && i=0 && while [ $i -lt %d ] && kill -0 1 2>/dev/null; do sleep 1; i=$((i+1)); done", service.StopSignal, seconds)
+	return &api.Lifecycle{
+		PreStop: &api.Handler{
+			Exec: &api.ExecAction{
+				Command: []string{"/bin/sh", "-c", script},
+			},
+		},
+	}
+}
+
+// ConfigTmpfs configure the tmpfs, and /dev/shm when the service sets shm_size.
+// Like Docker, the path of a tmpfs can be followed by options ("/run:size=64m,mode=1770").
 func (k *Kubernetes) ConfigTmpfs(name string, service kobject.ServiceConfig) ([]api.VolumeMount, []api.Volume) {
 	//initializing volumemounts and volumes
 	volumeMounts := []api.VolumeMount{}
 	volumes := []api.Volume{}
 
-	for index, volume := range service.TmpFs {
+	hasShm := false
+	for index, tmpfs := range service.TmpFs {
 		//naming volumes if multiple tmpfs are provided
 		volumeName := fmt.Sprintf("%s-tmpfs%d", name, index)
 
+		parts := strings.SplitN(tmpfs, ":", 2)
+		mountPath := parts[0]
+		if len(parts) == 2 {
+			reportTmpfsOptions(name, mountPath, parts[1])
+		}
+		if mountPath == "/dev/shm" {
+			hasShm = true
+		}
+
 		// create a new volume mount object and append to list
 		volMount := api.VolumeMount{
 			Name:      volumeName,
-			MountPath: volume,
+			MountPath: mountPath,
 		}
 		volumeMounts = append(volumeMounts, volMount)
 
@@ -369,9 +1207,128 @@ func (k *Kubernetes) ConfigTmpfs(name string, service kobject.ServiceConfig) ([]
 		}
 		volumes = append(volumes, vol)
 	}
+
+	// /dev/shm of a container is small, a larger one is a memory-backed emptyDir
+	if service.ShmSize != 0 && !hasShm {
+		volumeName := fmt.Sprintf("%s-shm", name)
+		volumeMounts = append(volumeMounts, api.VolumeMount{
+			Name:      volumeName,
+			MountPath: "/dev/shm",
+		})
+		volumes = append(volumes, api.Volume{
+			Name:         volumeName,
+			VolumeSource: *k.ConfigEmptyVolumeSource("tmpfs"),
+		})
+		log.Warningf("Unsupported size %s of the shm_size of service %q - ignoring, the sizeLimit of emptyDir volumes is not supported by this client", units.BytesSize(float64(service.ShmSize)), name)
+	}
+	return volumeMounts, volumes
+}
+
+// reportTmpfsOptions validates the options of a tmpfs and reports them,
+// as the emptyDir volume it is converted to has no mode, and no size with this client
+func reportTmpfsOptions(name string, mountPath string, options string) {
+	for _, option := range strings.Split(options, ",") {
+		parts := strings.SplitN(option, "=", 2)
+		switch {
+		case parts[0] == "size" && len(parts) == 2:
+			size, err := units.RAMInBytes(parts[1])
+			if err != nil {
+				log.Warningf("Invalid size %q of tmpfs %s of service %q - ignoring", parts[1], mountPath, name)
+				continue
+			}
+			log.Warningf("Unsupported size %s of tmpfs %s of service %q - ignoring, the sizeLimit of emptyDir volumes is not supported by this client", units.BytesSize(float64(size)), mountPath, name)
+		case parts[0] == "mode" && len(parts) == 2:
+			if _, err := strconv.ParseUint(parts[1], 8, 32); err != nil {
+				log.Warningf("Invalid mode %q of tmpfs %s of service %q - ignoring", parts[1], mountPath, name)
+				continue
+			}
+			log.Warningf("Unsupported mode %s of tmpfs %s of service %q - ignoring, emptyDir volumes are world-writable", parts[1], mountPath, name)
+		default:
+			log.Warningf("Unsupported option %q of tmpfs %s of service %q - ignoring", option, mountPath, name)
+		}
+	}
+}
+
+// configFileVolumes configures the volumes of file references, each one is mounted as a single file at its target.
+// The volumes are named after the service and kind, and volumeSource returns the source of the volume of a reference
+// given the item projecting the file.
+func configFileVolumes(name string, kind string, references []kobject.FileReference, volumeSource func(kobject.FileReference, []api.KeyToPath) api.VolumeSource) ([]api.VolumeMount, []api.Volume) {
+	volumeMounts := []api.VolumeMount{}
+	volumes := []api.Volume{}
+
+	for index, reference := range references {
+		volumeName := fmt.Sprintf("%s-%s%d", name, kind, index)
+		fileName := path.Base(reference.Target)
+
+		volumeMounts = append(volumeMounts, api.VolumeMount{
+			Name:      volumeName,
+			ReadOnly:  true,
+			MountPath: reference.Target,
+			SubPath:   fileName,
+		})
+
+		items := []api.KeyToPath{
+			{
+				Key:  reference.Name,
+				Path: fileName,
+				Mode: reference.Mode,
+			},
+		}
+		volumes = append(volumes, api.Volume{
+			Name:         volumeName,
+			VolumeSource: volumeSource(reference, items),
+		})
+	}
 	return volumeMounts, volumes
 }
 
+// ConfigFileVolumes configures the volumes of the secrets and configs of a service,
+// each one is mounted as a single file at its target
+func (k *Kubernetes) ConfigFileVolumes(name string, service kobject.ServiceConfig) ([]api.VolumeMount, []api.Volume) {
+	secretVolumeMounts, secretVolumes := configFileVolumes(name, "secret", service.Secrets, func(secret kobject.FileReference, items []api.KeyToPath) api.VolumeSource {
+		return api.VolumeSource{
+			Secret: &api.SecretVolumeSource{
+				SecretName: secret.Name,
+				Items:      items,
+			},
+		}
+	})
+	configVolumeMounts, configVolumes := configFileVolumes(name, "config", service.Configs, func(config kobject.FileReference, items []api.KeyToPath) api.VolumeSource {
+		return api.VolumeSource{
+			ConfigMap: &api.ConfigMapVolumeSource{
+				LocalObjectReference: api.LocalObjectReference{Name: config.Name},
+				Items:                items,
+			},
+		}
+	})
+	return append(secretVolumeMounts, configVolumeMounts...), append(secretVolumes, configVolumes...)
+}
+
+// ConfigFilesGroup returns the group owning the secrets and configs of a service, which becomes the fsGroup of the pod.
+// Kubernetes can't set the owner of a file projected from a Secret or a ConfigMap, so uid is ignored.
+func (k *Kubernetes) ConfigFilesGroup(name string, service kobject.ServiceConfig) *int64 {
+	var fsGroup *int64
+	for _, reference := range append(service.Secrets, service.Configs...) {
+		if reference.UID != "" && reference.UID != "0" {
+			log.Warningf("Ignoring uid of %q for service %q, the owner of a secret or config file can't be set", reference.Source, name)
+		}
+		if reference.GID == "" {
+			continue
+		}
+		gid, err := strconv.ParseInt(reference.GID, 10, 64)
+		if err != nil {
+			log.Warningf("Ignoring gid of %q for service %q. Invalid value %q.", reference.Source, name, reference.GID)
+			continue
+		}
+		if fsGroup != nil && *fsGroup != gid {
+			log.Warningf("Ignoring gid of %q for service %q, the secrets and configs of a service can only be owned by one group", reference.Source, name)
+			continue
+		}
+		fsGroup = &gid
+	}
+	return fsGroup
+}
+
 // ConfigVolumes configure the container volumes.
 func (k *Kubernetes) ConfigVolumes(name string, service kobject.ServiceConfig) ([]api.VolumeMount, []api.Volume, []*api.PersistentVolumeClaim, error) {
 	volumeMounts := []api.VolumeMount{}
@@ -390,10 +1347,25 @@ func (k *Kubernetes) ConfigVolumes(name string, service kobject.ServiceConfig) (
 		// check if ro/rw mode is defined, default rw
 		readonly := len(volume.Mode) > 0 && volume.Mode == "ro"
 
+		// the bind mounts follow the --bind-mounts strategy, the other volumes are PVCs, or emptyDirs with --emptyvols
+		bindMounts := ""
+		if volume.Type == kobject.VolumeTypeBind {
+			bindMounts = k.Opt.BindMounts
+			if bindMounts == kobject.BindMountsHostPath && !path.IsAbs(volume.Host) {
+				log.Warningf("Volume mount on the host %q can't be a hostPath volume, it isn't an absolute path - using an emptyDir volume", volume.Host)
+				bindMounts = kobject.BindMountsEmptyDir
+			}
+		}
+		useHostPath := bindMounts == kobject.BindMountsHostPath
+		useEmptyDir := useEmptyVolumes || bindMounts == kobject.BindMountsEmptyDir
+
 		if volume.VolumeName == "" {
-			if useEmptyVolumes {
+			switch {
+			case useHostPath:
+				volumeName = strings.Replace(volume.PVCName, "claim", "hostpath", 1)
+			case useEmptyDir:
 				volumeName = strings.Replace(volume.PVCName, "claim", "empty", 1)
-			} else {
+			default:
 				volumeName = volume.PVCName
 			}
 			count++
@@ -404,19 +1376,26 @@ func (k *Kubernetes) ConfigVolumes(name string, service kobject.ServiceConfig) (
 			Name:      volumeName,
 			ReadOnly:  readonly,
 			MountPath: volume.Container,
+			SubPath:   volume.SubPath,
 		}
 		volumeMounts = append(volumeMounts, volmount)
 		// Get a volume source based on the type of volume we are using
 		// For PVC we will also create a PVC object and add to list
 		var volsource *api.VolumeSource
 
-		if useEmptyVolumes {
+		if useHostPath {
+			volsource = &api.VolumeSource{
+				HostPath: &api.HostPathVolumeSource{Path: volume.Host},
+			}
+		} else if useEmptyDir {
 			volsource = k.ConfigEmptyVolumeSource("volume")
 		} else {
 
-			volsource = k.ConfigPVCVolumeSource(volumeName, readonly)
-			if volume.VFrom == "" {
-				createdPVC, err := k.CreatePVC(volumeName, volume.Mode)
+			volsource = k.ConfigPVCVolumeSource(volume.PVCName, readonly)
+			// the PVC of a volume shared with another service is created by that service,
+			// and the PVC of an external volume already exists
+			if volume.VFrom == "" && !volume.External {
+				createdPVC, err := k.CreatePVC(volume)
 
 				if err != nil {
 					return nil, nil, nil, errors.Wrap(err, "k.CreatePVC failed")
@@ -433,8 +1412,8 @@ func (k *Kubernetes) ConfigVolumes(name string, service kobject.ServiceConfig) (
 		}
 		volumes = append(volumes, vol)
 
-		if len(volume.Host) > 0 {
-			log.Warningf("Volume mount on the host %q isn't supported - ignoring path on the host", volume.Host)
+		if len(volume.Host) > 0 && !useHostPath {
+			log.Warningf("Volume mount on the host %q isn't supported - ignoring path on the host, use --bind-mounts=hostpath to mount it", volume.Host)
 		}
 
 	}
@@ -471,13 +1450,39 @@ func (k *Kubernetes) ConfigPVCVolumeSource(name string, readonly bool) *api.Volu
 }
 
 // ConfigEnvs configures the environment variables.
+// Variables from env_file reference the ConfigMap of their file, unless
+// they are also set in environment, which takes precedence.
 func (k *Kubernetes) ConfigEnvs(name string, service kobject.ServiceConfig) []api.EnvVar {
 	envs := transformer.EnvSort{}
+	found := make(map[string]bool)
 	for _, v := range service.Environment {
 		envs = append(envs, api.EnvVar{
 			Name:  v.Name,
 			Value: v.Value,
 		})
+		found[v.Name] = true
+	}
+
+	// a variable in a later env_file overrides the same variable in an earlier one
+	for i := len(service.EnvFile) - 1; i >= 0; i-- {
+		envFile := service.EnvFile[i]
+		for _, v := range envFile.Environment {
+			if found[v.Name] {
+				continue
+			}
+			envs = append(envs, api.EnvVar{
+				Name: v.Name,
+				ValueFrom: &api.EnvVarSource{
+					ConfigMapKeyRef: &api.ConfigMapKeySelector{
+						LocalObjectReference: api.LocalObjectReference{
+							Name: envFile.Name,
+						},
+						Key: v.Name,
+					},
+				},
+			})
+			found[v.Name] = true
+		}
 	}
 	// Stable sorts data while keeping the original order of equal elements
 	// we need this because envs are not populated in any random order
@@ -488,6 +1493,11 @@ func (k *Kubernetes) ConfigEnvs(name string, service kobject.ServiceConfig) []ap
 
 // CreateKubernetesObjects generates a Kubernetes artifact for each input type service
 func (k *Kubernetes) CreateKubernetesObjects(name string, service kobject.ServiceConfig, opt kobject.ConvertOptions) []runtime.Object {
+	// a global service runs a pod on every node, whatever the controllers of the other services
+	if service.DeployMode == "global" {
+		return []runtime.Object{k.InitDS(name, service)}
+	}
+
 	var objects []runtime.Object
 	var replica int
 	if opt.IsReplicaSetFlag || service.Replicas == 0 {
@@ -496,6 +1506,10 @@ func (k *Kubernetes) CreateKubernetesObjects(name string, service kobject.Servic
 		replica = service.Replicas
 	}
 
+	if service.Hostname != "" && replica > 1 {
+		log.Warningf("Service %q has a hostname and %d replicas, all the replicas will have the same hostname", name, replica)
+	}
+
 	if opt.CreateD {
 		objects = append(objects, k.InitD(name, service, replica))
 	}
@@ -599,11 +1613,39 @@ func (k *Kubernetes) Transform(komposeObject kobject.KomposeObject, opt kobject.
 			}
 		}
 
-		k.UpdateKubernetesObjects(name, service, &objects)
+		if err := k.UpdateKubernetesObjects(name, service, &objects); err != nil {
+			return nil, errors.Wrap(err, "k.UpdateKubernetesObjects failed")
+		}
+
+		if opt.WaitForDependencies || service.WaitForDependencies {
+			if err := k.UpdateInitContainers(name, service, komposeObject, objects); err != nil {
+				return nil, errors.Wrap(err, "k.UpdateInitContainers failed")
+			}
+		}
 
 		allobjects = append(allobjects, objects...)
 	}
 
+	if k.IgnoresDependsOn(komposeObject, opt) {
+		log.Warningf("Unsupported depends_on key - ignoring, use --wait-for-dependencies or the kompose.service.wait_for_dependencies label to wait for the dependencies with init containers")
+	}
+
+	subdomainServices, err := k.CreateSubdomainServices(komposeObject)
+	if err != nil {
+		return nil, errors.Wrap(err, "k.CreateSubdomainServices failed")
+	}
+	allobjects = append(allobjects, subdomainServices...)
+	allobjects = append(allobjects, k.CreateLinkServices(komposeObject, opt)...)
+
+	allobjects = append(allobjects, k.CreateSecrets(komposeObject)...)
+	allobjects = append(allobjects, k.CreateConfigConfigMaps(komposeObject)...)
+
+	if opt.NetworkPolicies {
+		allobjects = append(allobjects, k.CreateNetworkPolicies(komposeObject)...)
+	} else if k.UsesNetworks(komposeObject) {
+		log.Warningf("Unsupported networks key - ignoring, use --network-policies to convert the networks to NetworkPolicies")
+	}
+
 	// sort all object so Services are first
 	k.SortServicesFirst(&allobjects)
 	return allobjects, nil
@@ -709,6 +1751,12 @@ func (k *Kubernetes) Deploy(komposeObject kobject.KomposeObject, opt kobject.Con
 				return err
 			}
 			log.Infof("Successfully created Deployment: %s", t.Name)
+		case *extensions.DaemonSet:
+			_, err := client.DaemonSets(namespace).Create(t)
+			if err != nil {
+				return err
+			}
+			log.Infof("Successfully created DaemonSet: %s", t.Name)
 		case *api.Service:
 			_, err := client.Services(namespace).Create(t)
 			if err != nil {
@@ -720,13 +1768,32 @@ func (k *Kubernetes) Deploy(komposeObject kobject.KomposeObject, opt kobject.Con
 			if err != nil {
 				return err
 			}
-			log.Infof("Successfully created PersistentVolumeClaim: %s of size %s. If your cluster has dynamic storage provisioning, you don't have to do anything. Otherwise you have to create PersistentVolume to make PVC work", t.Name, PVCRequestSize)
+			size := t.Spec.Resources.Requests[api.ResourceStorage]
+			log.Infof("Successfully created PersistentVolumeClaim: %s of size %s. If your cluster has dynamic storage provisioning, you don't have to do anything. Otherwise you have to create PersistentVolume to make PVC work", t.Name, size.String())
+		case *api.ConfigMap:
+			_, err := client.ConfigMaps(namespace).Create(t)
+			if err != nil {
+				return err
+			}
+			log.Infof("Successfully created ConfigMap: %s", t.Name)
+		case *api.Secret:
+			_, err := client.Secrets(namespace).Create(t)
+			if err != nil {
+				return err
+			}
+			log.Infof("Successfully created Secret: %s", t.Name)
 		case *extensions.Ingress:
 			_, err := client.Ingress(namespace).Create(t)
 			if err != nil {
 				return err
 			}
 			log.Infof("Successfully created Ingress: %s", t.Name)
+		case *extensions.NetworkPolicy:
+			_, err := client.NetworkPolicies(namespace).Create(t)
+			if err != nil {
+				return err
+			}
+			log.Infof("Successfully created NetworkPolicy: %s", t.Name)
 		case *api.Pod:
 			_, err := client.Pods(namespace).Create(t)
 			if err != nil {
@@ -799,6 +1866,30 @@ func (k *Kubernetes) Undeploy(komposeObject kobject.KomposeObject, opt kobject.C
 				}
 			}
 
+		case *extensions.DaemonSet:
+			//delete daemonset
+			daemonSet, err := client.DaemonSets(namespace).List(options)
+			if err != nil {
+				errorList = append(errorList, err)
+				break
+			}
+			for _, l := range daemonSet.Items {
+				if reflect.DeepEqual(l.Labels, komposeLabel) {
+					rpDaemonSet, err := kubectl.ReaperFor(extensions.Kind("DaemonSet"), client)
+					if err != nil {
+						errorList = append(errorList, err)
+						break
+					}
+					//FIXME: gracePeriod is nil
+					err = rpDaemonSet.Stop(namespace, t.Name, TIMEOUT*time.Second, nil)
+					if err != nil {
+						errorList = append(errorList, err)
+						break
+					}
+					log.Infof("Successfully deleted DaemonSet: %s", t.Name)
+				}
+			}
+
 		case *api.Service:
 			//delete svc
 			svc, err := client.Services(namespace).List(options)
@@ -842,6 +1933,42 @@ func (k *Kubernetes) Undeploy(komposeObject kobject.KomposeObject, opt kobject.C
 				}
 			}
 
+		case *api.ConfigMap:
+			// delete configmap
+			configMap, err := client.ConfigMaps(namespace).List(options)
+			if err != nil {
+				errorList = append(errorList, err)
+				break
+			}
+			for _, l := range configMap.Items {
+				if reflect.DeepEqual(l.Labels, komposeLabel) {
+					err = client.ConfigMaps(namespace).Delete(t.Name)
+					if err != nil {
+						errorList = append(errorList, err)
+						break
+					}
+					log.Infof("Successfully deleted ConfigMap: %s", t.Name)
+				}
+			}
+
+		case *api.Secret:
+			// delete secret
+			secret, err := client.Secrets(namespace).List(options)
+			if err != nil {
+				errorList = append(errorList, err)
+				break
+			}
+			for _, l := range secret.Items {
+				if reflect.DeepEqual(l.Labels, komposeLabel) {
+					err = client.Secrets(namespace).Delete(t.Name)
+					if err != nil {
+						errorList = append(errorList, err)
+						break
+					}
+					log.Infof("Successfully deleted Secret: %s", t.Name)
+				}
+			}
+
 		case *extensions.Ingress:
 			// delete ingress
 			ingDeleteOptions := &api.DeleteOptions{
@@ -867,6 +1994,24 @@ func (k *Kubernetes) Undeploy(komposeObject kobject.KomposeObject, opt kobject.C
 				}
 			}
 
+		case *extensions.NetworkPolicy:
+			// delete network policy
+			networkPolicy, err := client.NetworkPolicies(namespace).List(options)
+			if err != nil {
+				errorList = append(errorList, err)
+				break
+			}
+			for _, l := range networkPolicy.Items {
+				if reflect.DeepEqual(l.Labels, komposeLabel) {
+					err = client.NetworkPolicies(namespace).Delete(t.Name, nil)
+					if err != nil {
+						errorList = append(errorList, err)
+						break
+					}
+					log.Infof("Successfully deleted NetworkPolicy: %s", t.Name)
+				}
+			}
+
 		case *api.Pod:
 			//delete pod
 			pod, err := client.Pods(namespace).List(options)
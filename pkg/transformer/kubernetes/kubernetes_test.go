@@ -17,6 +17,7 @@ limitations under the License.
 package kubernetes
 
 import (
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"testing"
@@ -29,6 +30,7 @@ import (
 	"github.com/pkg/errors"
 	"k8s.io/kubernetes/pkg/api"
 	"k8s.io/kubernetes/pkg/apis/extensions"
+	"k8s.io/kubernetes/pkg/util/intstr"
 )
 
 func newServiceConfig() kobject.ServiceConfig {
@@ -479,6 +481,63 @@ func TestRestartOnFailure(t *testing.T) {
 	}
 }
 
+func TestTransformInvalidPlacement(t *testing.T) {
+	komposeObject := newKomposeObject()
+	service := komposeObject.ServiceConfigs["app"]
+	service.Placement = kobject.Placement{
+		Constraints: []kobject.PlacementConstraint{{Key: "node.labels.disk", Operator: "==", Value: "fast ssd"}},
+	}
+	komposeObject.ServiceConfigs = map[string]kobject.ServiceConfig{"app": service}
+
+	k := Kubernetes{}
+	if _, err := k.Transform(komposeObject, kobject.ConvertOptions{CreateD: true, Replicas: 1}); err == nil {
+		t.Errorf("Expected an error for a placement constraint value which isn't a label value")
+	}
+}
+
+func TestCreateKubernetesObjectsGlobal(t *testing.T) {
+	opt := kobject.ConvertOptions{CreateD: true, Replicas: 1}
+	k := Kubernetes{}
+
+	objects := k.CreateKubernetesObjects("agent", kobject.ServiceConfig{Image: "agent", DeployMode: "global"}, opt)
+	if len(objects) != 1 {
+		t.Fatalf("Expected a single DaemonSet, got %#v", objects)
+	}
+	if _, ok := objects[0].(*extensions.DaemonSet); !ok {
+		t.Errorf("Expected a DaemonSet for a global service, got %T", objects[0])
+	}
+
+	objects = k.CreateKubernetesObjects("web", kobject.ServiceConfig{Image: "web", DeployMode: "replicated"}, opt)
+	if _, ok := objects[0].(*extensions.Deployment); len(objects) != 1 || !ok {
+		t.Errorf("Expected a single Deployment for a replicated service, got %#v", objects)
+	}
+}
+
+func TestTransformPodContainers(t *testing.T) {
+	proxy := kobject.ServiceConfig{ContainerName: "proxy", Image: "envoy", Port: []kobject.Ports{{HostPort: 8080, ContainerPort: 8080, Protocol: api.ProtocolTCP}}}
+	service := kobject.ServiceConfig{Image: "app", Port: []kobject.Ports{{HostPort: 80, ContainerPort: 80, Protocol: api.ProtocolTCP}}, Containers: []kobject.ServiceConfig{proxy}}
+	komposeObject := kobject.KomposeObject{ServiceConfigs: map[string]kobject.ServiceConfig{"app": service}}
+
+	k := Kubernetes{}
+	objects, err := k.Transform(komposeObject, kobject.ConvertOptions{CreateD: true, Replicas: 1})
+	if err != nil {
+		t.Fatalf("k.Transform failed: %v", err)
+	}
+	for _, obj := range objects {
+		switch o := obj.(type) {
+		case *api.Service:
+			if len(o.Spec.Ports) != 2 || o.Spec.Ports[1].Port != 8080 {
+				t.Errorf("Expected the Service to expose the ports of both containers, got %#v", o.Spec.Ports)
+			}
+		case *extensions.Deployment:
+			containers := o.Spec.Template.Spec.Containers
+			if len(containers) != 2 || containers[1].Name != "proxy" || containers[1].Image != "envoy" || containers[1].Ports[0].ContainerPort != 8080 {
+				t.Errorf("Expected the proxy container in the pod of app, got %#v", containers)
+			}
+		}
+	}
+}
+
 func TestInitPodSpec(t *testing.T) {
 	name := "foo"
 	k := Kubernetes{}
@@ -497,6 +556,64 @@ func TestConfigTmpfs(t *testing.T) {
 		t.Fatalf("Tmpfs not found")
 	}
 
+	// the options follow the path, and shm_size mounts a memory-backed emptyDir at /dev/shm
+	service := kobject.ServiceConfig{TmpFs: []string{"/run:size=64m,mode=1770"}, ShmSize: 1 << 30}
+	resultVolumeMount, resultVolume = k.ConfigTmpfs(name, service)
+	expectedVolumeMount := []api.VolumeMount{{Name: "foo-tmpfs0", MountPath: "/run"}, {Name: "foo-shm", MountPath: "/dev/shm"}}
+	if !reflect.DeepEqual(resultVolumeMount, expectedVolumeMount) {
+		t.Errorf("Expected volume mounts %v, got %v", expectedVolumeMount, resultVolumeMount)
+	}
+	if len(resultVolume) != 2 || resultVolume[1].Name != "foo-shm" || resultVolume[1].EmptyDir.Medium != api.StorageMediumMemory {
+		t.Errorf("Expected a memory-backed emptyDir for /dev/shm, got %v", resultVolume)
+	}
+}
+
+func TestConfigVolumesBindMounts(t *testing.T) {
+	service := kobject.ServiceConfig{
+		Volumes: []kobject.Volumes{
+			{SvcName: "web", VolumeName: "data", PVCName: "data", Container: "/html", Type: kobject.VolumeTypeVolume, SubPath: "html"},
+			{SvcName: "web", Host: "/srv/conf", Container: "/etc/conf", Mode: "ro", PVCName: "web-claim1", Type: kobject.VolumeTypeBind},
+			{SvcName: "web", Host: "./logs", Container: "/logs", PVCName: "web-claim2", Type: kobject.VolumeTypeBind},
+		},
+	}
+
+	testCases := map[string]struct {
+		bindMounts string
+		expected   []api.VolumeSource
+	}{
+		"pvc": {kobject.BindMountsPVC, []api.VolumeSource{
+			{PersistentVolumeClaim: &api.PersistentVolumeClaimVolumeSource{ClaimName: "data"}},
+			{PersistentVolumeClaim: &api.PersistentVolumeClaimVolumeSource{ClaimName: "web-claim1", ReadOnly: true}},
+			{PersistentVolumeClaim: &api.PersistentVolumeClaimVolumeSource{ClaimName: "web-claim2"}},
+		}},
+		// a relative path on the host can't be a hostPath volume
+		"hostpath": {kobject.BindMountsHostPath, []api.VolumeSource{
+			{PersistentVolumeClaim: &api.PersistentVolumeClaimVolumeSource{ClaimName: "data"}},
+			{HostPath: &api.HostPathVolumeSource{Path: "/srv/conf"}},
+			{EmptyDir: &api.EmptyDirVolumeSource{}},
+		}},
+		"emptydir": {kobject.BindMountsEmptyDir, []api.VolumeSource{
+			{PersistentVolumeClaim: &api.PersistentVolumeClaimVolumeSource{ClaimName: "data"}},
+			{EmptyDir: &api.EmptyDirVolumeSource{}},
+			{EmptyDir: &api.EmptyDirVolumeSource{}},
+		}},
+	}
+	for name, test := range testCases {
+		t.Log("Test case:", name)
+		k := Kubernetes{Opt: kobject.ConvertOptions{BindMounts: test.bindMounts}}
+		volumeMounts, volumes, _, err := k.ConfigVolumes("web", service)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		for i, volume := range volumes {
+			if !reflect.DeepEqual(volume.VolumeSource, test.expected[i]) {
+				t.Errorf("Expected volume source %#v for %s, got %#v", test.expected[i], volumeMounts[i].MountPath, volume.VolumeSource)
+			}
+		}
+		if volumeMounts[0].SubPath != "html" {
+			t.Errorf("Expected the html subpath, got %#v", volumeMounts[0])
+		}
+	}
 }
 
 func TestConfigCapabilities(t *testing.T) {
@@ -517,3 +634,504 @@ func TestConfigCapabilities(t *testing.T) {
 		}
 	}
 }
+
+func TestConfigSecurityOpts(t *testing.T) {
+	testCases := map[string]struct {
+		securityOpt         []string
+		expectedSELinux     *api.SELinuxOptions
+		expectedAnnotations map[string]string
+	}{
+		"None": {nil, nil, map[string]string{}},
+		"SELinux labels": {
+			[]string{"label:user:USER", "label=level:s0:c100,c200", "label:disable"},
+			&api.SELinuxOptions{User: "USER", Level: "s0:c100,c200"},
+			map[string]string{},
+		},
+		"AppArmor and seccomp profiles": {
+			[]string{"apparmor=nginx", "seccomp:/etc/seccomp/nginx.json", "no-new-privileges:true"},
+			nil,
+			map[string]string{
+				"container.apparmor.security.beta.kubernetes.io/web": "localhost/nginx",
+				"container.seccomp.security.alpha.kubernetes.io/web": "localhost/nginx.json",
+			},
+		},
+		"Default and unconfined profiles": {
+			[]string{"apparmor:docker-default", "seccomp=unconfined"},
+			nil,
+			map[string]string{
+				"container.apparmor.security.beta.kubernetes.io/web": "runtime/default",
+				"container.seccomp.security.alpha.kubernetes.io/web": "unconfined",
+			},
+		},
+	}
+
+	k := Kubernetes{}
+	for name, test := range testCases {
+		t.Log("Test case:", name)
+		seLinuxOptions, annotations := k.ConfigSecurityOpts("web", kobject.ServiceConfig{SecurityOpt: test.securityOpt})
+		if !reflect.DeepEqual(seLinuxOptions, test.expectedSELinux) {
+			t.Errorf("Expected SELinux options %#v, got %#v", test.expectedSELinux, seLinuxOptions)
+		}
+		if !reflect.DeepEqual(annotations, test.expectedAnnotations) {
+			t.Errorf("Expected annotations %v, got %v", test.expectedAnnotations, annotations)
+		}
+	}
+}
+
+func TestConfigSysctls(t *testing.T) {
+	service := kobject.ServiceConfig{
+		Sysctls: map[string]string{
+			"net.ipv4.tcp_syncookies": "0",
+			"net.core.somaxconn":      "1024",
+			"kernel.msgmax":           "65536",
+			"vm.swappiness":           "10",
+		},
+	}
+	expected := map[string]string{
+		api.SysctlsPodAnnotationKey:       "net.ipv4.tcp_syncookies=0",
+		api.UnsafeSysctlsPodAnnotationKey: "kernel.msgmax=65536,net.core.somaxconn=1024",
+	}
+
+	k := Kubernetes{}
+	if annotations := k.ConfigSysctls("web", service); !reflect.DeepEqual(annotations, expected) {
+		t.Errorf("Expected %v, got %v", expected, annotations)
+	}
+}
+
+func TestConfigPlacement(t *testing.T) {
+	service := kobject.ServiceConfig{
+		Placement: kobject.Placement{
+			Constraints: []kobject.PlacementConstraint{
+				{Key: "node.labels.disk", Operator: "==", Value: "ssd"},
+				{Key: "node.hostname", Operator: "!=", Value: "db-node"},
+				{Key: "node.role", Operator: "==", Value: "manager"},
+				{Key: "node.id", Operator: "==", Value: "2ivku8v2gvtg4"},
+			},
+			Preferences: []string{"node.labels.zone"},
+		},
+	}
+
+	k := Kubernetes{}
+	nodeSelector, annotations, err := k.ConfigPlacement("web", service)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := map[string]string{"disk": "ssd"}; !reflect.DeepEqual(nodeSelector, expected) {
+		t.Errorf("Expected node selector %v, got %v", expected, nodeSelector)
+	}
+
+	var affinity api.Affinity
+	if err := json.Unmarshal([]byte(annotations[api.AffinityAnnotationKey]), &affinity); err != nil {
+		t.Fatalf("Unable to unmarshal the affinity annotation: %v", err)
+	}
+	expectedRequirements := []api.NodeSelectorRequirement{
+		{Key: "kubernetes.io/hostname", Operator: api.NodeSelectorOpNotIn, Values: []string{"db-node"}},
+		{Key: MasterNodeLabel, Operator: api.NodeSelectorOpExists},
+	}
+	requirements := affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchExpressions
+	if !reflect.DeepEqual(requirements, expectedRequirements) {
+		t.Errorf("Expected node requirements %#v, got %#v", expectedRequirements, requirements)
+	}
+	preferences := affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution
+	if len(preferences) != 1 || preferences[0].PodAffinityTerm.TopologyKey != "zone" {
+		t.Errorf("Expected a pod anti-affinity spreading over zone, got %#v", preferences)
+	}
+}
+
+func TestConfigRollingUpdate(t *testing.T) {
+	testCases := map[string]struct {
+		updateConfig    *kobject.UpdateConfig
+		expected        *extensions.RollingUpdateDeployment
+		minReadySeconds int32
+	}{
+		"No update_config": {nil, nil, 0},
+		"Stop first": {
+			&kobject.UpdateConfig{Parallelism: 2, Delay: 10, Order: "stop-first"},
+			&extensions.RollingUpdateDeployment{MaxUnavailable: intstr.FromInt(2), MaxSurge: intstr.FromInt(0)},
+			10,
+		},
+		"Start first": {
+			&kobject.UpdateConfig{Parallelism: 1, Order: "start-first"},
+			&extensions.RollingUpdateDeployment{MaxUnavailable: intstr.FromInt(0), MaxSurge: intstr.FromInt(1)},
+			0,
+		},
+		"All at once": {
+			&kobject.UpdateConfig{Parallelism: 0, Order: "stop-first"},
+			&extensions.RollingUpdateDeployment{MaxUnavailable: intstr.FromString("100%"), MaxSurge: intstr.FromInt(0)},
+			0,
+		},
+	}
+
+	k := Kubernetes{}
+	for name, test := range testCases {
+		t.Log("Test case:", name)
+		rollingUpdate, minReadySeconds := k.ConfigRollingUpdate("web", kobject.ServiceConfig{UpdateConfig: test.updateConfig})
+		if !reflect.DeepEqual(rollingUpdate, test.expected) || minReadySeconds != test.minReadySeconds {
+			t.Errorf("Expected %#v and %d, got %#v and %d", test.expected, test.minReadySeconds, rollingUpdate, minReadySeconds)
+		}
+	}
+}
+
+func TestConfigCommand(t *testing.T) {
+	nofile := []kobject.Ulimit{{Name: "nproc", Soft: 65535, Hard: 65535}, {Name: "nofile", Soft: 20000, Hard: 40000}}
+	wrapper := []string{"sh", "-c", `ulimit -Hn 40000 && ulimit -Sn 20000; exec "$0" "$@"`}
+	testCases := map[string]struct {
+		service         kobject.ServiceConfig
+		expectedCommand []string
+		expectedArgs    []string
+	}{
+		"Not wrapped": {
+			kobject.ServiceConfig{Command: []string{"redis-server"}, Ulimits: nofile},
+			[]string{"redis-server"},
+			nil,
+		},
+		"Entrypoint": {
+			kobject.ServiceConfig{Command: []string{"docker-entrypoint.sh"}, Args: []string{"redis-server"}, Ulimits: nofile, WrapUlimitNofile: true},
+			append(wrapper, "docker-entrypoint.sh"),
+			[]string{"redis-server"},
+		},
+		// the entrypoint of the image would be replaced by the wrapper
+		"Command without entrypoint": {
+			kobject.ServiceConfig{Args: []string{"redis-server"}, Ulimits: nofile, WrapUlimitNofile: true},
+			nil,
+			[]string{"redis-server"},
+		},
+		"No command": {
+			kobject.ServiceConfig{Ulimits: nofile, WrapUlimitNofile: true},
+			nil,
+			nil,
+		},
+	}
+
+	k := Kubernetes{}
+	for name, test := range testCases {
+		t.Log("Test case:", name)
+		command, args := k.ConfigCommand("redis", test.service)
+		if !reflect.DeepEqual(command, test.expectedCommand) || !reflect.DeepEqual(args, test.expectedArgs) {
+			t.Errorf("Expected %q %q, got %q %q", test.expectedCommand, test.expectedArgs, command, args)
+		}
+	}
+}
+
+func TestConfigEnvs(t *testing.T) {
+	service := kobject.ServiceConfig{
+		Environment: []kobject.EnvVar{{Name: "foo", Value: "inline"}},
+		EnvFile: []kobject.EnvFile{
+			{Name: "web-a-env", Environment: []kobject.EnvVar{{Name: "foo", Value: "a"}, {Name: "bar", Value: "a"}, {Name: "baz", Value: "a"}}},
+			{Name: "web-b-env", Environment: []kobject.EnvVar{{Name: "bar", Value: "b"}}},
+		},
+	}
+	configMapRef := func(name, key string) *api.EnvVarSource {
+		return &api.EnvVarSource{
+			ConfigMapKeyRef: &api.ConfigMapKeySelector{
+				LocalObjectReference: api.LocalObjectReference{Name: name},
+				Key:                  key,
+			},
+		}
+	}
+	expected := []api.EnvVar{
+		{Name: "bar", ValueFrom: configMapRef("web-b-env", "bar")},
+		{Name: "baz", ValueFrom: configMapRef("web-a-env", "baz")},
+		{Name: "foo", Value: "inline"},
+	}
+
+	k := Kubernetes{}
+	result := k.ConfigEnvs("web", service)
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, result)
+	}
+
+	configMap := k.InitConfigMap(service.EnvFile[0])
+	if configMap.Name != "web-a-env" || configMap.Data["bar"] != "a" || len(configMap.Data) != 3 {
+		t.Errorf("Unexpected ConfigMap %#v", configMap)
+	}
+}
+
+func TestConfigFileVolumes(t *testing.T) {
+	mode := int32(0400)
+	service := kobject.ServiceConfig{
+		Secrets: []kobject.FileReference{
+			{Source: "db_password", Name: "db-password", Target: "/run/secrets/db_password"},
+			{Source: "tls_cert", Name: "tls-cert", Target: "/etc/nginx/cert.pem", GID: "1000", Mode: &mode},
+		},
+		Configs: []kobject.FileReference{
+			{Source: "nginx_conf", Name: "nginx-conf", Target: "/etc/nginx/nginx.conf", GID: "1000"},
+		},
+	}
+
+	k := Kubernetes{}
+	volumeMounts, volumes := k.ConfigFileVolumes("web", service)
+	if len(volumeMounts) != 3 || len(volumes) != 3 {
+		t.Fatalf("Expected 3 volumes, got %#v and %#v", volumeMounts, volumes)
+	}
+	expectedMount := api.VolumeMount{Name: "web-secret1", ReadOnly: true, MountPath: "/etc/nginx/cert.pem", SubPath: "cert.pem"}
+	if volumeMounts[1] != expectedMount {
+		t.Errorf("Expected %#v, got %#v", expectedMount, volumeMounts[1])
+	}
+	expectedSource := &api.SecretVolumeSource{
+		SecretName: "tls-cert",
+		Items:      []api.KeyToPath{{Key: "tls-cert", Path: "cert.pem", Mode: &mode}},
+	}
+	if volumes[1].Name != "web-secret1" || !reflect.DeepEqual(volumes[1].Secret, expectedSource) {
+		t.Errorf("Expected %#v, got %#v", expectedSource, volumes[1].Secret)
+	}
+	expectedConfigSource := &api.ConfigMapVolumeSource{
+		LocalObjectReference: api.LocalObjectReference{Name: "nginx-conf"},
+		Items:                []api.KeyToPath{{Key: "nginx-conf", Path: "nginx.conf"}},
+	}
+	if volumes[2].Name != "web-config0" || volumeMounts[2].SubPath != "nginx.conf" || !reflect.DeepEqual(volumes[2].ConfigMap, expectedConfigSource) {
+		t.Errorf("Expected %#v, got %#v", expectedConfigSource, volumes[2].ConfigMap)
+	}
+
+	fsGroup := k.ConfigFilesGroup("web", service)
+	if fsGroup == nil || *fsGroup != 1000 {
+		t.Errorf("Expected fsGroup 1000, got %v", fsGroup)
+	}
+}
+
+func TestCreateSecrets(t *testing.T) {
+	komposeObject := kobject.KomposeObject{
+		ServiceConfigs: map[string]kobject.ServiceConfig{
+			"web": {Secrets: []kobject.FileReference{{Source: "db_password"}, {Source: "api_key"}}},
+		},
+		Secrets: map[string]kobject.FileObject{
+			"db_password": {Name: "db-password", Data: []byte("password")},
+			"api_key":     {Name: "api-key", External: true},
+			"unused":      {Name: "unused", Data: []byte("unused")},
+		},
+	}
+
+	k := Kubernetes{}
+	objects := k.CreateSecrets(komposeObject)
+	if len(objects) != 1 {
+		t.Fatalf("Expected only the Secret of db_password, got %#v", objects)
+	}
+	secret := objects[0].(*api.Secret)
+	if secret.Name != "db-password" || string(secret.Data["db-password"]) != "password" {
+		t.Errorf("Unexpected Secret %#v", secret)
+	}
+}
+
+func TestCreateSubdomainServices(t *testing.T) {
+	komposeObject := kobject.KomposeObject{
+		ServiceConfigs: map[string]kobject.ServiceConfig{
+			"kafka":     {Hostname: "kafka", DomainName: "broker"},
+			"zookeeper": {Hostname: "zookeeper", DomainName: "broker"},
+			"web":       {Hostname: "web"},
+		},
+	}
+
+	k := Kubernetes{}
+	objects, err := k.CreateSubdomainServices(komposeObject)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(objects) != 1 {
+		t.Fatalf("Expected a single Service for the broker subdomain, got %#v", objects)
+	}
+	svc := objects[0].(*api.Service)
+	if svc.Name != "broker" || svc.Spec.ClusterIP != "None" || svc.Spec.Selector[SubdomainLabel] != "broker" {
+		t.Errorf("Unexpected Service %#v", svc)
+	}
+
+	// the Service of the subdomain would have the same name as the Service of web
+	komposeObject.ServiceConfigs["kafka"] = kobject.ServiceConfig{DomainName: "web"}
+	if _, err := k.CreateSubdomainServices(komposeObject); err == nil {
+		t.Errorf("Expected an error for a domainname which is the name of a service")
+	}
+}
+
+func TestCreateLinkServices(t *testing.T) {
+	komposeObject := kobject.KomposeObject{
+		ServiceConfigs: map[string]kobject.ServiceConfig{
+			"web": {
+				Links: []kobject.Link{{Service: "db", Alias: "database"}, {Service: "cache", Alias: "cache"}},
+				ExternalLinks: []kobject.Link{
+					{Service: "legacy_redis_1", Alias: "redis"},
+					{Service: "ldap", Alias: "ldap", ExternalName: "ldap.example.com"},
+				},
+			},
+			"worker": {
+				// the same alias of the same service, and an alias which is the name of a service
+				Links: []kobject.Link{{Service: "db", Alias: "database"}, {Service: "db", Alias: "cache"}},
+			},
+			"db":    {Port: []kobject.Ports{{ContainerPort: 5432, Protocol: api.ProtocolTCP}}},
+			"cache": {},
+		},
+	}
+
+	k := Kubernetes{}
+	objects := k.CreateLinkServices(komposeObject, kobject.ConvertOptions{ExternalLinksDomain: "example.com"})
+	if len(objects) != 3 {
+		t.Fatalf("Expected 3 Services, got %#v", objects)
+	}
+	svc := objects[0].(*api.Service)
+	if svc.Name != "database" || svc.Spec.Selector[transformer.Selector] != "db" || len(svc.Spec.Ports) != 1 || svc.Spec.Ports[0].Port != 5432 {
+		t.Errorf("Unexpected Service %#v", svc)
+	}
+	svc = objects[1].(*api.Service)
+	if svc.Name != "redis" || svc.Spec.Type != api.ServiceTypeExternalName || svc.Spec.ExternalName != "legacy-redis-1.example.com" {
+		t.Errorf("Unexpected Service %#v", svc)
+	}
+	svc = objects[2].(*api.Service)
+	if svc.Name != "ldap" || svc.Spec.ExternalName != "ldap.example.com" {
+		t.Errorf("Unexpected Service %#v", svc)
+	}
+}
+
+func TestConfigPortsHostMode(t *testing.T) {
+	service := kobject.ServiceConfig{
+		Port: []kobject.Ports{
+			{HostPort: 8443, ContainerPort: 443, Protocol: api.ProtocolTCP},
+			{HostPort: 9100, ContainerPort: 9100, Protocol: api.ProtocolTCP, Mode: kobject.PortModeHost},
+		},
+	}
+
+	k := Kubernetes{}
+	expectedPorts := []api.ContainerPort{
+		{ContainerPort: 443},
+		{ContainerPort: 9100, HostPort: 9100},
+	}
+	if ports := k.ConfigPorts("web", service); !reflect.DeepEqual(ports, expectedPorts) {
+		t.Errorf("Expected %#v, got %#v", expectedPorts, ports)
+	}
+
+	// the port published in host mode isn't a port of the Service
+	servicePorts := k.ConfigServicePorts("web", service)
+	if len(servicePorts) != 1 || servicePorts[0].Port != 8443 {
+		t.Errorf("Expected the 8443 Service port only, got %#v", servicePorts)
+	}
+
+	service.Port = service.Port[1:]
+	if k.PortsExist("web", service) {
+		t.Errorf("Expected a headless Service for a service with host mode ports only")
+	}
+}
+
+func TestConfigStopSignal(t *testing.T) {
+	k := Kubernetes{}
+	if lifecycle := k.ConfigStopSignal(kobject.ServiceConfig{StopSignal: "TERM"}, nil); lifecycle != nil {
+		t.Errorf("Expected no preStop hook for SIGTERM, got %#v", lifecycle)
+	}
+
+	gracePeriod := int64(20)
+	testCases := map[string]struct {
+		gracePeriod *int64
+		expected    string
+	}{
+		"stop_grace_period":    {&gracePeriod, "kill -QUIT 1 && i=0 && while [ $i -lt 20 ] && kill -0 1 2>/dev/null; do sleep 1; i=$((i+1)); done"},
+		"default grace period": {nil, "kill -QUIT 1 && i=0 && while [ $i -lt 30 ] && kill -0 1 2>/dev/null; do sleep 1; i=$((i+1)); done"},
+	}
+	for name, test := range testCases {
+		t.Log("Test case:", name)
+		lifecycle := k.ConfigStopSignal(kobject.ServiceConfig{StopSignal: "QUIT"}, test.gracePeriod)
+		if lifecycle == nil || lifecycle.PreStop == nil || lifecycle.PreStop.Exec == nil {
+			t.Fatalf("Expected a preStop exec hook, got %#v", lifecycle)
+		}
+		expected := []string{"/bin/sh", "-c", test.expected}
+		if !reflect.DeepEqual(lifecycle.PreStop.Exec.Command, expected) {
+			t.Errorf("Expected %q, got %q", expected, lifecycle.PreStop.Exec.Command)
+		}
+	}
+}
+
+func TestCreateConfigConfigMaps(t *testing.T) {
+	komposeObject := kobject.KomposeObject{
+		ServiceConfigs: map[string]kobject.ServiceConfig{
+			"web": {Configs: []kobject.FileReference{{Source: "nginx_conf"}}},
+		},
+		Configs: map[string]kobject.FileObject{
+			"nginx_conf": {Name: "nginx-conf", Data: []byte("events {}")},
+			"unused":     {Name: "unused", Data: []byte("unused")},
+		},
+	}
+
+	k := Kubernetes{}
+	objects := k.CreateConfigConfigMaps(komposeObject)
+	if len(objects) != 1 {
+		t.Fatalf("Expected only the ConfigMap of nginx_conf, got %#v", objects)
+	}
+	configMap := objects[0].(*api.ConfigMap)
+	if configMap.Name != "nginx-conf" || configMap.Data["nginx-conf"] != "events {}" {
+		t.Errorf("Unexpected ConfigMap %#v", configMap)
+	}
+}
+
+func TestInitWaitContainers(t *testing.T) {
+	komposeObject := kobject.KomposeObject{
+		ServiceConfigs: map[string]kobject.ServiceConfig{
+			"web": {DependsOn: []kobject.ServiceDependency{
+				{Service: "api", Condition: kobject.ServiceHealthy},
+				{Service: "cache", Condition: kobject.ServiceStarted},
+				{Service: "db", Condition: kobject.ServiceHealthy},
+				{Service: "migrate", Condition: kobject.ServiceStarted},
+			}},
+			"api":     {Port: []kobject.Ports{{HostPort: 80, ContainerPort: 8000, Protocol: api.ProtocolTCP}}, HealthChecks: kobject.HealthCheck{HTTPGetPath: "/health", HTTPGetPort: 8000}},
+			"cache":   {},
+			"db":      {Port: []kobject.Ports{{ContainerPort: 5432, Protocol: api.ProtocolTCP}}},
+			"migrate": {Restart: "no"},
+		},
+	}
+
+	k := Kubernetes{}
+	containers, err := k.InitWaitContainers("web", komposeObject.ServiceConfigs["web"], komposeObject)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := map[string]string{
+		"wait-for-api":   "until wget -q -O /dev/null http://api:80/health; do echo waiting for api; sleep 2; done",
+		"wait-for-cache": "until nslookup cache; do echo waiting for cache; sleep 2; done",
+		"wait-for-db":    "until nc -z db 5432; do echo waiting for db; sleep 2; done",
+	}
+	if len(containers) != len(expected) {
+		t.Fatalf("Expected %d init containers, got %#v", len(expected), containers)
+	}
+	for _, container := range containers {
+		if container.Image != WaitImage || container.Command[2] != expected[container.Name] {
+			t.Errorf("Unexpected init container %#v", container)
+		}
+	}
+
+	service := kobject.ServiceConfig{DependsOn: []kobject.ServiceDependency{{Service: "undefined"}}}
+	if _, err := k.InitWaitContainers("web", service, komposeObject); err == nil {
+		t.Errorf("Expected an error for an undefined dependency")
+	}
+}
+
+func TestCreateNetworkPolicies(t *testing.T) {
+	komposeObject := kobject.KomposeObject{
+		ServiceConfigs: map[string]kobject.ServiceConfig{
+			"web": {Network: []string{"front"}, Port: []kobject.Ports{{HostPort: 80, ContainerPort: 8080, Protocol: api.ProtocolTCP}}},
+			"db":  {Network: []string{"back"}, Port: []kobject.Ports{{HostPort: 5432, ContainerPort: 5432, Protocol: api.ProtocolTCP}}},
+		},
+		NetworkConfigs: map[string]kobject.NetworkConfig{
+			"front": {},
+			"back":  {Internal: true},
+		},
+	}
+
+	k := Kubernetes{}
+	objects := k.CreateNetworkPolicies(komposeObject)
+	if len(objects) != 2 {
+		t.Fatalf("Expected 2 NetworkPolicies, got %d", len(objects))
+	}
+
+	back := objects[0].(*extensions.NetworkPolicy)
+	if back.Name != "back" || back.Labels[transformer.Selector] != "back" || back.Spec.PodSelector.MatchLabels["io.kompose.network/back"] != "true" {
+		t.Errorf("Unexpected NetworkPolicy %#v", back)
+	}
+	// the published ports of an internal network aren't reachable from outside
+	if len(back.Spec.Ingress) != 1 || back.Spec.Ingress[0].From[0].PodSelector.MatchLabels["io.kompose.network/back"] != "true" {
+		t.Errorf("Unexpected ingress rules %#v", back.Spec.Ingress)
+	}
+
+	front := objects[1].(*extensions.NetworkPolicy)
+	if len(front.Spec.Ingress) != 2 || len(front.Spec.Ingress[1].From) != 0 || front.Spec.Ingress[1].Ports[0].Port.IntVal != 8080 {
+		t.Errorf("Unexpected ingress rules %#v", front.Spec.Ingress)
+	}
+
+	labels := ConfigNetworkLabels(komposeObject.ServiceConfigs["web"])
+	if !reflect.DeepEqual(labels, map[string]string{"io.kompose.network/front": "true"}) {
+		t.Errorf("Unexpected labels %v", labels)
+	}
+}
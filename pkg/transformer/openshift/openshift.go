@@ -29,6 +29,7 @@ import (
 
 	kapi "k8s.io/kubernetes/pkg/api"
 	"k8s.io/kubernetes/pkg/api/unversioned"
+	"k8s.io/kubernetes/pkg/apis/extensions"
 	"k8s.io/kubernetes/pkg/client/unversioned/clientcmd"
 	"k8s.io/kubernetes/pkg/runtime"
 
@@ -204,6 +205,11 @@ func initBuildConfig(name string, service kobject.ServiceConfig, repo string, br
 		return nil, errors.Wrap(err, name+"buildconfig cannot be created due to error in creating build context, getAbsBuildContext failed")
 	}
 
+	// BuildConfig's Docker strategy has no equivalent for these
+	if service.BuildTarget != "" || len(service.BuildLabels) > 0 || len(service.BuildCacheFrom) > 0 {
+		log.Warnf("build target, labels and cache_from of service %s are not supported in BuildConfig - ignoring", name)
+	}
+
 	bc := &buildapi.BuildConfig{
 		TypeMeta: unversioned.TypeMeta{
 			Kind:       "BuildConfig",
@@ -399,7 +405,8 @@ func (o *OpenShift) Transform(komposeObject kobject.KomposeObject, opt kobject.C
 		} else {
 			objects = o.CreateKubernetesObjects(name, service, opt)
 
-			if opt.CreateDeploymentConfig {
+			// a global service is a DaemonSet, created by CreateKubernetesObjects
+			if opt.CreateDeploymentConfig && service.DeployMode != "global" {
 				objects = append(objects, o.initDeploymentConfig(name, service, replica)) // OpenShift DeploymentConfigs
 				// create ImageStream after deployment (creating IS will trigger new deployment)
 				objects = append(objects, o.initImageStream(name, service, opt))
@@ -407,7 +414,10 @@ func (o *OpenShift) Transform(komposeObject kobject.KomposeObject, opt kobject.C
 
 			// buildconfig needs to be added to objects after imagestream because of this Openshift bug: https://github.com/openshift/origin/issues/4518
 			// Generate BuildConfig if the parameter has been passed
-			if service.Build != "" && opt.Build == "build-config" {
+			if service.Build != "" && opt.Build == "build-config" && service.DeployMode == "global" {
+				// a DaemonSet has no ImageStream for the BuildConfig to push to, nor a trigger to deploy what it builds
+				log.Warningf("Unsupported build key of global service %q - ignoring, --build build-config needs a DeploymentConfig", name)
+			} else if service.Build != "" && opt.Build == "build-config" {
 
 				// Get the compose file directory
 				composeFileDir, err = transformer.GetComposeFileDir(opt.InputFiles)
@@ -466,10 +476,36 @@ func (o *OpenShift) Transform(komposeObject kobject.KomposeObject, opt kobject.C
 		}
 
 		// Update and then append the objects (we're done generating)
-		o.UpdateKubernetesObjects(name, service, &objects)
+		if err := o.UpdateKubernetesObjects(name, service, &objects); err != nil {
+			return nil, errors.Wrap(err, "o.UpdateKubernetesObjects failed")
+		}
+		if opt.WaitForDependencies || service.WaitForDependencies {
+			if err := o.UpdateInitContainers(name, service, komposeObject, objects); err != nil {
+				return nil, errors.Wrap(err, "o.UpdateInitContainers failed")
+			}
+		}
 		allobjects = append(allobjects, objects...)
 	}
 
+	if o.IgnoresDependsOn(komposeObject, opt) {
+		log.Warningf("Unsupported depends_on key - ignoring, use --wait-for-dependencies or the kompose.service.wait_for_dependencies label to wait for the dependencies with init containers")
+	}
+
+	subdomainServices, err := o.CreateSubdomainServices(komposeObject)
+	if err != nil {
+		return nil, errors.Wrap(err, "o.CreateSubdomainServices failed")
+	}
+	allobjects = append(allobjects, subdomainServices...)
+	allobjects = append(allobjects, o.CreateLinkServices(komposeObject, opt)...)
+
+	allobjects = append(allobjects, o.CreateSecrets(komposeObject)...)
+	allobjects = append(allobjects, o.CreateConfigConfigMaps(komposeObject)...)
+
+	// --network-policies is a Kubernetes only flag
+	if o.UsesNetworks(komposeObject) {
+		log.Warningf("OpenShift provider doesn't support networks key - ignoring")
+	}
+
 	// sort all object so Services are first
 	o.SortServicesFirst(&allobjects)
 
@@ -540,6 +576,12 @@ func (o *OpenShift) Deploy(komposeObject kobject.KomposeObject, opt kobject.Conv
 				return err
 			}
 			log.Infof("Successfully created DeploymentConfig: %s", t.Name)
+		case *extensions.DaemonSet:
+			_, err := kclient.DaemonSets(namespace).Create(t)
+			if err != nil {
+				return err
+			}
+			log.Infof("Successfully created DaemonSet: %s", t.Name)
 		case *kapi.Service:
 			_, err := kclient.Services(namespace).Create(t)
 			if err != nil {
@@ -551,7 +593,20 @@ func (o *OpenShift) Deploy(komposeObject kobject.KomposeObject, opt kobject.Conv
 			if err != nil {
 				return err
 			}
-			log.Infof("Successfully created PersistentVolumeClaim: %s of size %s. If your cluster has dynamic storage provisioning, you don't have to do anything. Otherwise you have to create PersistentVolume to make PVC work", t.Name, kubernetes.PVCRequestSize)
+			size := t.Spec.Resources.Requests[kapi.ResourceStorage]
+			log.Infof("Successfully created PersistentVolumeClaim: %s of size %s. If your cluster has dynamic storage provisioning, you don't have to do anything. Otherwise you have to create PersistentVolume to make PVC work", t.Name, size.String())
+		case *kapi.ConfigMap:
+			_, err := kclient.ConfigMaps(namespace).Create(t)
+			if err != nil {
+				return err
+			}
+			log.Infof("Successfully created ConfigMap: %s", t.Name)
+		case *kapi.Secret:
+			_, err := kclient.Secrets(namespace).Create(t)
+			if err != nil {
+				return err
+			}
+			log.Infof("Successfully created Secret: %s", t.Name)
 		case *routeapi.Route:
 			_, err := oclient.Routes(namespace).Create(t)
 			if err != nil {
@@ -664,6 +719,29 @@ func (o *OpenShift) Undeploy(komposeObject kobject.KomposeObject, opt kobject.Co
 				}
 			}
 
+		case *extensions.DaemonSet:
+			// delete daemonSet
+			daemonSet, err := kclient.DaemonSets(namespace).List(options)
+			if err != nil {
+				errorList = append(errorList, err)
+				break
+			}
+			for _, l := range daemonSet.Items {
+				if reflect.DeepEqual(l.Labels, komposeLabel) {
+					rpDaemonSet, err := kubectl.ReaperFor(extensions.Kind("DaemonSet"), kclient)
+					if err != nil {
+						errorList = append(errorList, err)
+						break
+					}
+					err = rpDaemonSet.Stop(namespace, t.Name, TIMEOUT*time.Second, nil)
+					if err != nil {
+						errorList = append(errorList, err)
+						break
+					}
+					log.Infof("Successfully deleted DaemonSet: %s", t.Name)
+				}
+			}
+
 		case *kapi.Service:
 			//delete svc
 			svc, err := kclient.Services(namespace).List(options)
@@ -706,6 +784,42 @@ func (o *OpenShift) Undeploy(komposeObject kobject.KomposeObject, opt kobject.Co
 				}
 			}
 
+		case *kapi.ConfigMap:
+			// delete configmap
+			configMap, err := kclient.ConfigMaps(namespace).List(options)
+			if err != nil {
+				errorList = append(errorList, err)
+				break
+			}
+			for _, l := range configMap.Items {
+				if reflect.DeepEqual(l.Labels, komposeLabel) {
+					err = kclient.ConfigMaps(namespace).Delete(t.Name)
+					if err != nil {
+						errorList = append(errorList, err)
+						break
+					}
+					log.Infof("Successfully deleted ConfigMap: %s", t.Name)
+				}
+			}
+
+		case *kapi.Secret:
+			// delete secret
+			secret, err := kclient.Secrets(namespace).List(options)
+			if err != nil {
+				errorList = append(errorList, err)
+				break
+			}
+			for _, l := range secret.Items {
+				if reflect.DeepEqual(l.Labels, komposeLabel) {
+					err = kclient.Secrets(namespace).Delete(t.Name)
+					if err != nil {
+						errorList = append(errorList, err)
+						break
+					}
+					log.Infof("Successfully deleted Secret: %s", t.Name)
+				}
+			}
+
 		case *routeapi.Route:
 			// delete route
 			route, err := oclient.Routes(namespace).List(options)
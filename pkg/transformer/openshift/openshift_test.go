@@ -400,6 +400,23 @@ func TestRestartOnFailure(t *testing.T) {
 
 }
 
+func TestTransformInvalidPlacement(t *testing.T) {
+	service := kobject.ServiceConfig{
+		Image: "nginx",
+		Placement: kobject.Placement{
+			Constraints: []kobject.PlacementConstraint{{Key: "node.labels.disk", Operator: "==", Value: "fast ssd"}},
+		},
+	}
+	komposeObject := kobject.KomposeObject{
+		ServiceConfigs: map[string]kobject.ServiceConfig{"app": service},
+	}
+
+	o := OpenShift{}
+	if _, err := o.Transform(komposeObject, kobject.ConvertOptions{CreateDeploymentConfig: true, Replicas: 1}); err == nil {
+		t.Errorf("Expected an error for a placement constraint value which isn't a label value")
+	}
+}
+
 // Tests if deployment strategy is being set to Recreate when volumes are
 // present
 func TestRecreateStrategyWithVolumesPresent(t *testing.T) {
@@ -24,6 +24,7 @@ import (
 	"strings"
 
 	log "github.com/Sirupsen/logrus"
+	dockerlib "github.com/fsouza/go-dockerclient"
 	"github.com/kubernetes/kompose/pkg/kobject"
 
 	"github.com/kubernetes/kompose/pkg/utils/docker"
@@ -201,12 +202,31 @@ func BuildDockerImage(service kobject.ServiceConfig, name string, relativePath s
 
 	// Get the appropriate image source and name
 	// use path.Base to get the last element of the relative build path
+	// an absolute build path is already resolved against the compose file directory
 	imagePath := path.Join(relativePath, path.Base(service.Build))
+	if filepath.IsAbs(service.Build) {
+		imagePath = service.Build
+	}
 	imageName := name
 	if service.Image != "" {
 		imageName = service.Image
 	}
 
+	// Build arguments without a value are taken from the environment
+	var buildArgs []dockerlib.BuildArg
+	for argName, argValue := range service.BuildArgs {
+		value := *argValue
+		if value == "\x00" {
+			value = os.Getenv(argName)
+		}
+		buildArgs = append(buildArgs, dockerlib.BuildArg{Name: argName, Value: value})
+	}
+
+	// These are not supported by the Docker client used for building
+	if service.BuildTarget != "" || len(service.BuildLabels) > 0 || len(service.BuildCacheFrom) > 0 {
+		log.Warnf("build target, labels and cache_from of service %s are not supported when building locally - ignoring", name)
+	}
+
 	// Connect to the Docker client
 	client, err := docker.DockerClient()
 	if err != nil {
@@ -216,7 +236,7 @@ func BuildDockerImage(service kobject.ServiceConfig, name string, relativePath s
 	// Use the build struct function to build the image
 	// Build the image!
 	build := docker.Build{Client: *client}
-	err = build.BuildImage(imagePath, imageName)
+	err = build.BuildImage(imagePath, imageName, service.Dockerfile, buildArgs)
 
 	if err != nil {
 		return err
@@ -34,11 +34,12 @@ type Build struct {
 }
 
 /*
-BuildImage builds a Docker image via the Docker API. Takes the source directory
-and image name and then builds the appropriate image. Tarball is utilized
+BuildImage builds a Docker image via the Docker API. Takes the source directory,
+image name, Dockerfile (relative to the source directory, empty for the default one)
+and build arguments and then builds the appropriate image. Tarball is utilized
 in order to make building easier.
 */
-func (c *Build) BuildImage(source string, image string) error {
+func (c *Build) BuildImage(source string, image string, dockerfile string, buildArgs []dockerlib.BuildArg) error {
 
 	log.Infof("Building image '%s' from directory '%s'", image, path.Base(source))
 
@@ -65,6 +66,8 @@ func (c *Build) BuildImage(source string, image string) error {
 	outputBuffer := bytes.NewBuffer(nil)
 	opts := dockerlib.BuildImageOptions{
 		Name:         image,
+		Dockerfile:   dockerfile,
+		BuildArgs:    buildArgs,
 		InputStream:  tarballSource,
 		OutputStream: outputBuffer,
 	}
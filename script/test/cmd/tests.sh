@@ -112,6 +112,12 @@ convert::expect_success "kompose -f $KOMPOSE_ROOT/script/test/fixtures/volume-mo
 # openshift test
 convert::expect_success "kompose --provider=openshift -f $KOMPOSE_ROOT/script/test/fixtures/volume-mounts/volumes-from/docker-compose-case.yml convert --stdout -j" "$KOMPOSE_ROOT/script/test/fixtures/volume-mounts/volumes-from/output-os-case.json"
 
+# Tests related to docker-compose file in /script/test/fixtures/volume-mounts/named-volumes
+# kubernetes test
+convert::expect_success "kompose -f $KOMPOSE_ROOT/script/test/fixtures/volume-mounts/named-volumes/docker-compose.yml convert --stdout -j" "$KOMPOSE_ROOT/script/test/fixtures/volume-mounts/named-volumes/output-k8s.json"
+# kubernetes test with a version 3 file
+convert::expect_success "kompose -f $KOMPOSE_ROOT/script/test/fixtures/volume-mounts/named-volumes/docker-compose-v3.yml convert --stdout -j" "$KOMPOSE_ROOT/script/test/fixtures/volume-mounts/named-volumes/output-k8s-v3.json"
+
 
 ######
 # Tests related to docker-compose file in /script/test/fixtures/envvars-separators
@@ -135,6 +141,8 @@ convert::expect_failure "kompose convert $KOMPOSE_ROOT/script/test/fixtures/gitl
 convert::expect_success_and_warning "kompose -f $KOMPOSE_ROOT/script/test/fixtures/multiple-compose-files/docker-k8s.yml -f $KOMPOSE_ROOT/script/test/fixtures/multiple-compose-files/docker-os.yml convert --stdout -j" "$KOMPOSE_ROOT/script/test/fixtures/multiple-compose-files/output-k8s.json" "Unsupported depends_on key - ignoring"
 # OpenShift test
 convert::expect_success_and_warning "kompose --provider=openshift -f $KOMPOSE_ROOT/script/test/fixtures/multiple-compose-files/docker-k8s.yml -f $KOMPOSE_ROOT/script/test/fixtures/multiple-compose-files/docker-os.yml convert --stdout -j" "$KOMPOSE_ROOT/script/test/fixtures/multiple-compose-files/output-openshift.json" "Unsupported depends_on key - ignoring"
+# v3 files are merged by kompose, values of the later file override or extend the earlier one
+convert::expect_success "kompose -f $KOMPOSE_ROOT/script/test/fixtures/multiple-compose-files/docker-k8s-v3.yml -f $KOMPOSE_ROOT/script/test/fixtures/multiple-compose-files/docker-os-v3.yml convert --stdout -j" "$KOMPOSE_ROOT/script/test/fixtures/multiple-compose-files/output-k8s-v3.json"
 
 
 ######
@@ -153,6 +161,11 @@ export $(cat $KOMPOSE_ROOT/script/test/fixtures/keyonly-envs/envs)
 convert::expect_success "kompose --file $KOMPOSE_ROOT/script/test/fixtures/keyonly-envs/env.yml convert --stdout -j" "$KOMPOSE_ROOT/script/test/fixtures/keyonly-envs/output-k8s.json"
 unset $(cat $KOMPOSE_ROOT/script/test/fixtures/keyonly-envs/envs | cut -d'=' -f1)
 
+######
+# Test env_file converted to ConfigMaps, inline environment takes precedence
+convert::expect_success "kompose -f $KOMPOSE_ROOT/script/test/fixtures/env-file/docker-compose.yml convert --stdout -j" "$KOMPOSE_ROOT/script/test/fixtures/env-file/output-k8s.json"
+convert::expect_success "kompose -f $KOMPOSE_ROOT/script/test/fixtures/env-file/docker-compose-v3.yml convert --stdout -j" "$KOMPOSE_ROOT/script/test/fixtures/env-file/output-k8s.json"
+
 #####
 # Test related to host:port:container in docker-compose
 # kubernetes test
@@ -199,9 +212,9 @@ convert::expect_success "kompose --provider openshift -f $KOMPOSE_ROOT/script/te
 
 # Test the change in the service name
 # Kubernetes Test
-convert::expect_success_and_warning "kompose -f $KOMPOSE_ROOT/script/test/fixtures/service-name-change/docker-compose.yml convert --stdout -j" "$KOMPOSE_ROOT/script/test/fixtures/service-name-change/output-k8s.json" "Unsupported root level volumes key - ignoring"
+convert::expect_success_and_warning "kompose -f $KOMPOSE_ROOT/script/test/fixtures/service-name-change/docker-compose.yml convert --stdout -j" "$KOMPOSE_ROOT/script/test/fixtures/service-name-change/output-k8s.json" "Unsupported depends_on key - ignoring"
 # Openshift Test
-convert::expect_success_and_warning "kompose --provider openshift -f $KOMPOSE_ROOT/script/test/fixtures/service-name-change/docker-compose.yml convert --stdout -j" "$KOMPOSE_ROOT/script/test/fixtures/service-name-change/output-os.json" "Unsupported root level volumes key - ignoring"
+convert::expect_success_and_warning "kompose --provider openshift -f $KOMPOSE_ROOT/script/test/fixtures/service-name-change/docker-compose.yml convert --stdout -j" "$KOMPOSE_ROOT/script/test/fixtures/service-name-change/output-os.json" "Unsupported depends_on key - ignoring"
 
 # Test regarding validating dockerfilepath
 convert::expect_failure "kompose -f $KOMPOSE_ROOT/script/test/fixtures/dockerfilepath/docker-compose.yml convert --stdout"
@@ -243,6 +256,8 @@ rm /tmp/output-os.json
 sed -e "s;%URI%;$uri;g" -e "s;%REF%;$branch;g" $KOMPOSE_ROOT/script/test/fixtures/buildargs/output-os-template.json > /tmp/output-buildarg-os.json
 export $(cat $KOMPOSE_ROOT/script/test/fixtures/buildargs/envs)
 convert::expect_success_and_warning "kompose --provider openshift -f $KOMPOSE_ROOT/script/test/fixtures/buildargs/docker-compose.yml convert --stdout -j --build build-config" "/tmp/output-buildarg-os.json" "$warning"
+# v3 build is converted the same way
+convert::expect_success_and_warning "kompose --provider openshift -f $KOMPOSE_ROOT/script/test/fixtures/buildargs/docker-compose-v3.yml convert --stdout -j --build build-config" "/tmp/output-buildarg-os.json" "$warning"
 rm /tmp/output-buildarg-os.json
 
 ####
@@ -282,6 +297,77 @@ convert::expect_success "kompose convert --stdout -j -f $KOMPOSE_ROOT/script/tes
 # Test environment variables substitution
 convert::expect_success "kompose convert --stdout -j -f $KOMPOSE_ROOT/script/test/fixtures/v3/docker-compose-env-subs.yaml" "$KOMPOSE_ROOT/script/test/fixtures/v3/output-env-subs.json"
 
+# Test the NetworkPolicies of the networks
+convert::expect_success "kompose convert --stdout -j --network-policies -f $KOMPOSE_ROOT/script/test/fixtures/network-policies/docker-compose.yml" "$KOMPOSE_ROOT/script/test/fixtures/network-policies/output-k8s.json"
+convert::expect_success "kompose convert --stdout -j --network-policies -f $KOMPOSE_ROOT/script/test/fixtures/network-policies/docker-compose-v3.yml" "$KOMPOSE_ROOT/script/test/fixtures/network-policies/output-k8s.json"
+
+# Test secrets converted to Secrets mounted as files, external secrets are only referenced
+convert::expect_success "kompose convert --stdout -j -f $KOMPOSE_ROOT/script/test/fixtures/secrets/docker-compose.yml" "$KOMPOSE_ROOT/script/test/fixtures/secrets/output-k8s.json"
+convert::expect_failure "kompose convert --stdout -j -f $KOMPOSE_ROOT/script/test/fixtures/secrets/docker-compose-undefined.yml"
+
+# Test configs converted to ConfigMaps mounted as files, external configs are only referenced
+convert::expect_success "kompose convert --stdout -j -f $KOMPOSE_ROOT/script/test/fixtures/configs/docker-compose.yml" "$KOMPOSE_ROOT/script/test/fixtures/configs/output-k8s.json"
+
+# Test init containers waiting for the dependencies of depends_on, with --wait-for-dependencies or the kompose.service.wait_for_dependencies label
+convert::expect_success "kompose convert --stdout -j --wait-for-dependencies -f $KOMPOSE_ROOT/script/test/fixtures/depends-on/docker-compose.yml" "$KOMPOSE_ROOT/script/test/fixtures/depends-on/output-k8s.json"
+convert::expect_success_and_warning "kompose convert --stdout -j -f $KOMPOSE_ROOT/script/test/fixtures/depends-on/docker-compose-v3.yml" "$KOMPOSE_ROOT/script/test/fixtures/depends-on/output-k8s-v3.json" "Unsupported depends_on key - ignoring"
+
+# Test hostname and domainname, the pods of a domainname are selected by a headless Service named after it
+convert::expect_success "kompose convert --stdout -j -f $KOMPOSE_ROOT/script/test/fixtures/hostname/docker-compose.yml" "$KOMPOSE_ROOT/script/test/fixtures/hostname/output-k8s.json"
+convert::expect_warning "kompose convert --stdout -j -f $KOMPOSE_ROOT/script/test/fixtures/hostname/docker-compose-replicas.yml" "all the replicas will have the same hostname"
+
+# Test sysctls, set by pod annotations, and ulimits, of which only nofile can be set by wrapping the command
+convert::expect_success_and_warning "kompose convert --stdout -j -f $KOMPOSE_ROOT/script/test/fixtures/sysctls-ulimits/docker-compose.yml" "$KOMPOSE_ROOT/script/test/fixtures/sysctls-ulimits/output-k8s.json" "are unsafe"
+convert::expect_warning "kompose convert --stdout -j -f $KOMPOSE_ROOT/script/test/fixtures/sysctls-ulimits/docker-compose-v3.yml" "Unsupported ulimit nproc"
+convert::expect_warning "kompose convert --stdout -j -f $KOMPOSE_ROOT/script/test/fixtures/sysctls-ulimits/docker-compose-v3.yml" "the entrypoint of the image can't be wrapped"
+
+# Test deploy.mode, a global service is a DaemonSet next to the Deployments or DeploymentConfigs of the other services
+convert::expect_success "kompose convert --stdout -j -f $KOMPOSE_ROOT/script/test/fixtures/deploy-mode/docker-compose.yml" "$KOMPOSE_ROOT/script/test/fixtures/deploy-mode/output-k8s.json"
+convert::expect_success "kompose convert --provider=openshift --stdout -j -f $KOMPOSE_ROOT/script/test/fixtures/deploy-mode/docker-compose.yml" "$KOMPOSE_ROOT/script/test/fixtures/deploy-mode/output-os.json"
+convert::expect_warning "kompose convert --provider=openshift --stdout -j -f $KOMPOSE_ROOT/script/test/fixtures/deploy-mode/docker-compose-build.yml --build build-config --build-repo https://example.com/foo/bar.git --build-branch master" "Unsupported build key of global service \"node-exporter\" - ignoring"
+
+# Test network_mode "service:<name>" and the kompose.pod.group label, converted to multi-container pods
+convert::expect_success_and_warning "kompose convert --stdout -j -f $KOMPOSE_ROOT/script/test/fixtures/pod-group/docker-compose.yml" "$KOMPOSE_ROOT/script/test/fixtures/pod-group/output-k8s.json" "Unsupported depends_on key - ignoring"
+convert::expect_success_and_warning "kompose convert --provider=openshift --stdout -j -f $KOMPOSE_ROOT/script/test/fixtures/pod-group/docker-compose.yml" "$KOMPOSE_ROOT/script/test/fixtures/pod-group/output-os.json" "Unsupported depends_on key - ignoring"
+
+# Test shm_size and the tmpfs options, converted to memory-backed emptyDir volumes
+convert::expect_success_and_warning "kompose convert --stdout -j -f $KOMPOSE_ROOT/script/test/fixtures/shm-size/docker-compose.yml" "$KOMPOSE_ROOT/script/test/fixtures/shm-size/output-k8s.json" "Unsupported size 2 GiB of the shm_size"
+convert::expect_success_and_warning "kompose convert --stdout -j -f $KOMPOSE_ROOT/script/test/fixtures/shm-size/docker-compose-v3.yml" "$KOMPOSE_ROOT/script/test/fixtures/shm-size/output-k8s-v3.json" "Unsupported size 9.537 MiB of tmpfs /var/run/postgresql"
+
+# Test links and external_links, their aliases are Services selecting the linked pods or ExternalName Services
+convert::expect_success "kompose convert --stdout -j -f $KOMPOSE_ROOT/script/test/fixtures/links/docker-compose.yml --external-links-domain legacy.example.com" "$KOMPOSE_ROOT/script/test/fixtures/links/output-k8s.json"
+convert::expect_warning "kompose convert --stdout -j -f $KOMPOSE_ROOT/script/test/fixtures/links/docker-compose.yml" "set its DNS name with the kompose.service.external_link.redis label or --external-links-domain"
+
+# Test port ranges, expanded into a port each, and the ports published in host mode, which are host ports
+convert::expect_success "kompose convert --stdout -j -f $KOMPOSE_ROOT/script/test/fixtures/port-ranges/docker-compose.yml" "$KOMPOSE_ROOT/script/test/fixtures/port-ranges/output-k8s.json"
+convert::expect_success "kompose convert --stdout -j -f $KOMPOSE_ROOT/script/test/fixtures/port-ranges/docker-compose-v3.yml" "$KOMPOSE_ROOT/script/test/fixtures/port-ranges/output-k8s-v3.json"
+
+# Test the volume types, bind mounts follow --bind-mounts, and the subpath of the v3 long syntax is kept
+convert::expect_success_and_warning "kompose convert --stdout -j -f $KOMPOSE_ROOT/script/test/fixtures/volume-types/docker-compose-v3.yml --bind-mounts hostpath" "$KOMPOSE_ROOT/script/test/fixtures/volume-types/output-k8s-v3-hostpath.json" "Unsupported consistency \"cached\" of volume /etc/nginx/conf.d"
+convert::expect_warning "kompose convert --stdout -j -f $KOMPOSE_ROOT/script/test/fixtures/volume-types/docker-compose-v3.yml --bind-mounts hostpath" "Unsupported nocopy of volume /usr/share/nginx/html"
+convert::expect_success_and_warning "kompose convert --stdout -j -f $KOMPOSE_ROOT/script/test/fixtures/volume-types/docker-compose.yml --bind-mounts emptydir" "$KOMPOSE_ROOT/script/test/fixtures/volume-types/output-k8s-emptydir.json" "isn't supported - ignoring path on the host"
+convert::expect_failure "kompose convert --stdout -j -f $KOMPOSE_ROOT/script/test/fixtures/volume-types/docker-compose.yml --bind-mounts nfs"
+
+# Test stop_signal, sent by a preStop hook waiting for stop_grace_period
+convert::expect_success "kompose convert --stdout -j -f $KOMPOSE_ROOT/script/test/fixtures/stop-signal/docker-compose.yml" "$KOMPOSE_ROOT/script/test/fixtures/stop-signal/output-k8s.json"
+
+# Test security_opt, read_only and group_add, converted to security contexts and AppArmor and seccomp annotations
+convert::expect_success_and_warning "kompose convert --stdout -j -f $KOMPOSE_ROOT/script/test/fixtures/security-opt/docker-compose.yml" "$KOMPOSE_ROOT/script/test/fixtures/security-opt/output-k8s.json" "Ignoring group \"mail\" of group_add"
+
+# Test deploy.update_config, converted to rolling updates, and deploy.rollback_config, which is reported
+convert::expect_success_and_warning "kompose convert --stdout -j -f $KOMPOSE_ROOT/script/test/fixtures/update-config/docker-compose.yml" "$KOMPOSE_ROOT/script/test/fixtures/update-config/output-k8s.json" "Unsupported deploy.rollback_config key"
+convert::expect_success_and_warning "kompose convert --provider=openshift --stdout -j -f $KOMPOSE_ROOT/script/test/fixtures/update-config/docker-compose.yml" "$KOMPOSE_ROOT/script/test/fixtures/update-config/output-os.json" "Unsupported deploy.update_config monitor, failure_action"
+
+# Test deploy.placement, converted to node selectors and affinities
+convert::expect_success "kompose convert --stdout -j -f $KOMPOSE_ROOT/script/test/fixtures/placement/docker-compose.yml" "$KOMPOSE_ROOT/script/test/fixtures/placement/output-k8s.json"
+
+# Test minor versions of Docker Compose, 2.x is parsed with libcompose and 3.x with docker/cli
+convert::expect_success_and_warning "kompose convert --stdout -j -f $KOMPOSE_ROOT/script/test/fixtures/compose-versions/docker-compose-v2.4.yml" "$KOMPOSE_ROOT/script/test/fixtures/compose-versions/output-k8s.json" "Unsupported init key - ignoring"
+convert::expect_success_and_warning "kompose convert --stdout -j -f $KOMPOSE_ROOT/script/test/fixtures/compose-versions/docker-compose-v3.7.yml" "$KOMPOSE_ROOT/script/test/fixtures/compose-versions/output-k8s.json" "Unsupported init key - ignoring"
+
+# Test that a key added after the version of the file fails
+convert::expect_failure "kompose convert --stdout -j -f $KOMPOSE_ROOT/script/test/fixtures/compose-versions/docker-compose-too-recent-key.yml"
+
 # Test that two files that are different versions fail
 convert::expect_failure "kompose convert --stdout -j -f $KOMPOSE_ROOT/script/test/fixtures/v3/docker-compose.yaml -f $KOMPOSE_ROOT/script/test/fixtures/etherpad/docker-compose.yml"
 
@@ -299,4 +385,8 @@ convert::expect_success_and_warning "kompose convert --stdout -j -f $KOMPOSE_ROO
 # Openshift
 convert::expect_success_and_warning "kompose convert --provider=openshift --stdout -j -f $KOMPOSE_ROOT/script/test/fixtures/v3/docker-compose-full-example.yaml" "$KOMPOSE_ROOT/script/test/fixtures/v3/output-os-full-example.json"
 
+# Test the unsupported keys of the "full example", which fail the conversion with --error-on-warning
+convert::expect_warning "kompose convert --stdout -j -f $KOMPOSE_ROOT/script/test/fixtures/v3/docker-compose-full-example.yaml" "Unsupported devices key - ignoring"
+convert::expect_failure "kompose --error-on-warning convert --stdout -j -f $KOMPOSE_ROOT/script/test/fixtures/v3/docker-compose-full-example.yaml"
+
 exit $EXIT_STATUS